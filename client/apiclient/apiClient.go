@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -16,15 +19,45 @@ import (
 	"github.com/shkotk/gochat/common/apimodels/responses"
 )
 
+const (
+	// Delay before the first attempt to restore lost connection, doubled after each attempt.
+	reconnectDelay = time.Second
+
+	// Number of attempts to restore lost connection.
+	reconnectAttempts = 5
+
+	// Number of missed messages fetched from chat history at once.
+	backfillPageSize = 100
+
+	// Time given to send close message when leaving.
+	closeWriteTimeout = time.Second
+
+	// Prefix of direct chat names, followed by sorted usernames of participants
+	// separated by ':'.
+	directChatPrefix = "dm:"
+)
+
 type ApiClient struct {
 	client http.Client
 	host   string
 	token  token
 
+	// Name of the logged in user.
+	username string
+
 	chattingLock sync.Mutex
 	conn         *websocket.Conn
+	connLock     sync.Mutex
+	leaving      atomic.Bool
 	in           chan any
 	out          chan any
+	done         chan struct{}
+
+	// Sequence numbers of the last messages received in each chat and chats
+	// subscribed to over current connection, used to resume after reconnect.
+	lastSeq       map[string]uint64
+	subscriptions map[string]struct{}
+	sessionLock   sync.Mutex
 }
 
 func New(host string) *ApiClient {
@@ -66,10 +99,16 @@ func (c *ApiClient) Login(authRequest requests.Auth) (time.Time, error) {
 	}
 
 	c.token.Set(tokenResponse.Token)
+	c.username = authRequest.Username
 
 	return tokenResponse.ExpiresAt, nil
 }
 
+// Gets name of the logged in user.
+func (c *ApiClient) Username() string {
+	return c.username
+}
+
 func (c *ApiClient) RefreshToken() (time.Time, error) {
 	u := url.URL{Scheme: "https", Host: c.host, Path: "/token/refresh"}
 	request, err := http.NewRequest(http.MethodGet, u.String(), nil)
@@ -154,48 +193,413 @@ func (c *ApiClient) Create(chatName string) error {
 	return nil
 }
 
-func (c *ApiClient) Join(chatName string) error {
-	if !c.chattingLock.TryLock() {
-		return errors.New("can't join more then one chat at once")
+// Gets page of chat messages sent before message with provided sequence number,
+// or latest messages if before is zero.
+func (c *ApiClient) GetHistory(
+	chatName string, before uint64, limit int,
+) (responses.Messages, error) {
+	query := url.Values{}
+	if before != 0 {
+		query.Set("before", strconv.FormatUint(before, 10))
+	}
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	return c.getHistory(chatName, query)
+}
+
+// Gets page of chat messages sent after message with provided sequence number.
+func (c *ApiClient) GetHistoryAfter(
+	chatName string, after uint64, limit int,
+) (responses.Messages, error) {
+	query := url.Values{}
+	query.Set("after", strconv.FormatUint(after, 10))
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	return c.getHistory(chatName, query)
+}
+
+func (c *ApiClient) getHistory(chatName string, query url.Values) (responses.Messages, error) {
+	path := "/chat/history/" + url.PathEscape(chatName)
+	// direct chat names aren't valid chat names, so their history is keyed by peer
+	if peer, ok := c.directChatPeer(chatName); ok {
+		path = "/dm/history/" + url.PathEscape(peer)
+	}
+
+	u := url.URL{
+		Scheme:   "https",
+		Host:     c.host,
+		Path:     path,
+		RawQuery: query.Encode(),
+	}
+	request, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return responses.Messages{}, err
+	}
+
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.token.Get()))
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return responses.Messages{}, err
 	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return responses.Messages{}, extractError(response, "get history")
+	}
+
+	messagesResponse := responses.Messages{}
+	err = json.NewDecoder(response.Body).Decode(&messagesResponse)
+	if err != nil {
+		return responses.Messages{}, err
+	}
+
+	return messagesResponse, nil
+}
 
+// Gets message and replies to it in chronological order.
+func (c *ApiClient) GetThread(chatName, messageID string) (responses.Thread, error) {
 	u := url.URL{
-		Scheme: "wss",
+		Scheme: "https",
 		Host:   c.host,
-		Path:   "/chat/join/" + url.PathEscape(chatName),
+		Path:   "/chat/thread/" + url.PathEscape(chatName) + "/" + url.PathEscape(messageID),
 	}
-	conn, response, err := websocket.DefaultDialer.Dial(u.String(), http.Header{
-		"Authorization": []string{fmt.Sprintf("Bearer %s", c.token.Get())},
-	})
+	request, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return responses.Thread{}, err
+	}
+
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.token.Get()))
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return responses.Thread{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return responses.Thread{}, extractError(response, "get thread")
+	}
+
+	threadResponse := responses.Thread{}
+	err = json.NewDecoder(response.Body).Decode(&threadResponse)
+	if err != nil {
+		return responses.Thread{}, err
+	}
+
+	return threadResponse, nil
+}
+
+// Gets users currently in the chat.
+func (c *ApiClient) GetMembers(chatName string) ([]string, error) {
+	u := url.URL{Scheme: "https", Host: c.host, Path: "/chat/members/" + url.PathEscape(chatName)}
+	request, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.token.Get()))
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, extractError(response, "get members")
+	}
+
+	membersResponse := responses.Members{}
+	err = json.NewDecoder(response.Body).Decode(&membersResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	return membersResponse.Members, nil
+}
+
+// Gets the latest notifications, newest first, and number of unread ones.
+func (c *ApiClient) GetNotifications() (responses.Notifications, error) {
+	u := url.URL{Scheme: "https", Host: c.host, Path: "/notification/list"}
+	request, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return responses.Notifications{}, err
+	}
+
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.token.Get()))
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return responses.Notifications{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return responses.Notifications{}, extractError(response, "get notifications")
+	}
+
+	notificationsResponse := responses.Notifications{}
+	err = json.NewDecoder(response.Body).Decode(&notificationsResponse)
+	if err != nil {
+		return responses.Notifications{}, err
+	}
+
+	return notificationsResponse, nil
+}
+
+// Marks notifications with provided IDs as read, or all of them if no IDs are provided.
+func (c *ApiClient) MarkNotificationsRead(ids ...string) error {
+	jsonBody, err := json.Marshal(requests.MarkNotificationsRead{IDs: ids})
 	if err != nil {
 		return err
 	}
-	if response.StatusCode != http.StatusSwitchingProtocols {
-		return fmt.Errorf("got join response with unexpected status code '%v'", response.Status)
+
+	u := url.URL{Scheme: "https", Host: c.host, Path: "/notification/read"}
+	request, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(jsonBody))
+	if err != nil {
+		return err
 	}
 
-	c.conn = conn
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.token.Get()))
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return extractError(response, "mark notifications read")
+	}
+
+	return nil
+}
+
+func (c *ApiClient) ClearNotifications() error {
+	u := url.URL{Scheme: "https", Host: c.host, Path: "/notification/clear"}
+	request, err := http.NewRequest(http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.token.Get()))
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return extractError(response, "clear notifications")
+	}
+
+	return nil
+}
+
+// Connects to a single chat. Only one connection can be active at once.
+// Connection is restored automatically if it's lost, resuming from the last received message.
+func (c *ApiClient) Join(chatName string) error {
+	return c.open(func() string {
+		path := "/chat/join/" + url.PathEscape(chatName)
+		if lastSeq := c.getLastSeq(chatName); lastSeq != 0 {
+			path += "?lastSeq=" + strconv.FormatUint(lastSeq, 10)
+		}
+		return path
+	}, nil)
+}
+
+// Opens connection which can be used to follow several chats, see Subscribe and
+// Unsubscribe. Only one connection can be active at once. Connection is restored
+// automatically if it's lost, resubscribing to chats and resuming from the last
+// received messages.
+func (c *ApiClient) Connect() error {
+	return c.open(func() string { return "/chat/connect" }, c.resubscribe)
+}
+
+// Starts receiving events of the chat over connection opened with Connect.
+func (c *ApiClient) Subscribe(chatName string) error {
+	err := c.WriteEvent(&events.Subscribe{Chat: chatName})
+	if err != nil {
+		return err
+	}
+
+	c.sessionLock.Lock()
+	c.subscriptions[chatName] = struct{}{}
+	c.sessionLock.Unlock()
+
+	return nil
+}
+
+// Stops receiving events of the chat over connection opened with Connect.
+func (c *ApiClient) Unsubscribe(chatName string) error {
+	c.sessionLock.Lock()
+	delete(c.subscriptions, chatName)
+	c.sessionLock.Unlock()
+
+	return c.WriteEvent(&events.Unsubscribe{Chat: chatName})
+}
+
+// Opens connection using path returned by provided function and starts pumping events.
+// afterReconnect is called, if not nil, once lost connection is re-established.
+func (c *ApiClient) open(
+	path func() string, afterReconnect func(*websocket.Conn) error,
+) error {
+	if !c.chattingLock.TryLock() {
+		return errors.New("can't open more then one connection at once")
+	}
+
+	c.sessionLock.Lock()
+	c.lastSeq = make(map[string]uint64)
+	c.subscriptions = make(map[string]struct{})
+	c.sessionLock.Unlock()
+
+	conn, err := c.dial(path())
+	if err != nil {
+		c.chattingLock.Unlock()
+		return err
+	}
+
+	c.leaving.Store(false)
 	c.in = make(chan any)
 	c.out = make(chan any)
+	c.done = make(chan struct{})
 
-	go c.readLoop()
-	go c.writeLoop()
+	go c.run(conn, path, afterReconnect)
 
 	return nil
 }
 
-func (c *ApiClient) readLoop() {
+func (c *ApiClient) dial(path string) (*websocket.Conn, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = "wss"
+	u.Host = c.host
+
+	conn, response, err := websocket.DefaultDialer.Dial(u.String(), http.Header{
+		"Authorization": []string{fmt.Sprintf("Bearer %s", c.token.Get())},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("got join response with unexpected status code '%v'", response.Status)
+	}
+
+	c.connLock.Lock()
+	c.conn = conn
+	c.connLock.Unlock()
+
+	return conn, nil
+}
+
+// Pumps events over connection, reconnecting if connection is lost unexpectedly.
+// Closes in channel once client leaves or connection can't be restored.
+func (c *ApiClient) run(
+	conn *websocket.Conn, path func() string, afterReconnect func(*websocket.Conn) error,
+) {
 	defer func() {
+		close(c.done)
 		close(c.in)
-		c.in = nil
-		close(c.out)
+		c.chattingLock.Unlock()
 	}()
 
 	for {
-		mt, message, err := c.conn.ReadMessage()
+		err := c.serve(conn)
+		if c.leaving.Load() || websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+			return
+		}
+		// server dropped connection on purpose, e.g. for flooding, reconnecting won't help
+		if closeErr := (*websocket.CloseError)(nil); errors.As(err, &closeErr) &&
+			(closeErr.Code == events.CloseKicked || closeErr.Code == events.CloseFlooding) {
+			c.in <- &events.SystemMessage{
+				Text: fmt.Sprintf("disconnected by server: %s", closeErr.Text), Time: time.Now()}
+			return
+		}
+
+		c.in <- &events.SystemMessage{Text: "connection lost, reconnecting...", Time: time.Now()}
+		conn = c.reconnect(path, afterReconnect)
+		if conn == nil {
+			return
+		}
+		c.in <- &events.SystemMessage{Text: "reconnected", Time: time.Now()}
+	}
+}
+
+// Re-establishes connection with exponential backoff.
+// Returns nil if all attempts failed or client left meanwhile.
+func (c *ApiClient) reconnect(
+	path func() string, afterReconnect func(*websocket.Conn) error,
+) *websocket.Conn {
+	delay := reconnectDelay
+	for attempt := 0; attempt < reconnectAttempts; attempt++ {
+		time.Sleep(delay)
+		delay *= 2
+
+		if c.leaving.Load() {
+			return nil
+		}
+
+		conn, err := c.dial(path())
+		if err != nil {
+			continue
+		}
+		if afterReconnect != nil {
+			if err = afterReconnect(conn); err != nil {
+				conn.Close()
+				continue
+			}
+		}
+
+		return conn
+	}
+
+	return nil
+}
+
+// Subscribes to chats followed before reconnect, resuming from the last received messages.
+func (c *ApiClient) resubscribe(conn *websocket.Conn) error {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+
+	for chatName := range c.subscriptions {
+		message, err := events.Serialize(
+			&events.Subscribe{Chat: chatName, LastSeq: c.lastSeq[chatName]})
+		if err != nil {
+			return err
+		}
+		if err = conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Reads and writes events over connection until it's closed. Returns read error.
+func (c *ApiClient) serve(conn *websocket.Conn) error {
+	stopWriting := make(chan struct{})
+	defer close(stopWriting)
+
+	go c.writeLoop(conn, stopWriting)
+
+	return c.readLoop(conn)
+}
+
+func (c *ApiClient) readLoop(conn *websocket.Conn) error {
+	for {
+		mt, message, err := conn.ReadMessage()
 		if err != nil {
 			// TODO log
-			return
+			return err
 		}
 		if mt != websocket.TextMessage {
 			// TODO log
@@ -208,32 +612,133 @@ func (c *ApiClient) readLoop() {
 			continue
 		}
 
+		switch event := event.(type) {
+		case *events.NewMessage:
+			if lastSeq := c.getLastSeq(event.Chat); lastSeq != 0 && event.Seq > lastSeq+1 {
+				c.backfill(event.Chat, event.Seq)
+			}
+			if !c.trackSeq(event) {
+				continue // already received before reconnect
+			}
+		case *events.ReplayTruncated:
+			c.backfill(event.Chat, 0)
+			continue
+		}
+
 		c.in <- event
 	}
 }
 
-func (c *ApiClient) writeLoop() {
-	defer func() {
-		c.out = nil
-		c.chattingLock.Unlock()
-	}()
+func (c *ApiClient) writeLoop(conn *websocket.Conn, stop <-chan struct{}) {
+	for {
+		select {
+		case event := <-c.out:
+			message, err := events.Serialize(event)
+			if err != nil {
+				// TODO log
+				continue
+			}
+
+			err = conn.WriteMessage(websocket.TextMessage, message)
+			if err != nil {
+				// TODO log
+				conn.Close()
+				return
+			}
+
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Remembers sequence number of the message. Returns false if message with
+// the same or greater sequence number was already received.
+func (c *ApiClient) trackSeq(message *events.NewMessage) bool {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+
+	if message.Seq <= c.lastSeq[message.Chat] {
+		return false
+	}
+	c.lastSeq[message.Chat] = message.Seq
+
+	return true
+}
 
-	for event := range c.out {
-		message, err := events.Serialize(event)
+// Fetches messages following the last received one from chat history and passes
+// them on, until message with sequence number until or, if it's zero, the latest
+// message is reached.
+func (c *ApiClient) backfill(chatName string, until uint64) {
+	for {
+		page, err := c.GetHistoryAfter(chatName, c.getLastSeq(chatName), backfillPageSize)
 		if err != nil {
-			// TODO log
-			continue
+			c.in <- &events.Error{
+				Chat: chatName,
+				Text: fmt.Sprintf("missed messages weren't loaded: %s", err),
+				Time: time.Now(),
+			}
+			return
 		}
 
-		err = c.conn.WriteMessage(websocket.TextMessage, message)
-		if err != nil {
-			// TODO log
+		for _, message := range page.Messages {
+			if until != 0 && message.Seq >= until {
+				return
+			}
+
+			event := toNewMessage(chatName, message)
+			if c.trackSeq(event) {
+				c.in <- event
+			}
+		}
+
+		if !page.HasMore || len(page.Messages) == 0 {
 			return
 		}
 	}
+}
+
+// Gets the other participant of direct chat. Returns false if chat isn't direct.
+func (c *ApiClient) directChatPeer(chatName string) (string, bool) {
+	usernames, ok := strings.CutPrefix(chatName, directChatPrefix)
+	if !ok {
+		return "", false
+	}
+
+	first, second, _ := strings.Cut(usernames, ":")
+	if first == c.username {
+		return second, true
+	}
+	return first, true
+}
+
+func toNewMessage(chatName string, message responses.Message) *events.NewMessage {
+	reactions := make([]events.ReactionCount, len(message.Reactions))
+	for i, reaction := range message.Reactions {
+		reactions[i] = events.ReactionCount{Emoji: reaction.Emoji, Count: reaction.Count}
+	}
+
+	return &events.NewMessage{
+		ID:        message.ID,
+		Seq:       message.Seq,
+		Chat:      chatName,
+		Producer:  message.Producer,
+		Time:      message.Time,
+		Text:      message.Text,
+		ReplyTo:   message.ReplyTo,
+		Mentions:  message.Mentions,
+		Action:    message.Action,
+		Edited:    message.Edited,
+		Deleted:   message.Deleted,
+		Reactions: reactions,
+	}
+}
 
-	// out channel was closed from outside
-	c.conn.WriteMessage(websocket.CloseMessage, nil)
+func (c *ApiClient) getLastSeq(chatName string) uint64 {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+
+	return c.lastSeq[chatName]
 }
 
 func (c *ApiClient) ReadEvent() (event any, more bool, err error) {
@@ -257,13 +762,23 @@ func (c *ApiClient) WriteEvent(event any) error {
 	select {
 	case c.out <- event:
 		return nil
+	case <-c.done:
+		return errors.New("connection is closed, can't write event")
 	case <-time.After(time.Minute):
 		return errors.New("timed out writing event")
 	}
 }
 
 func (c *ApiClient) Leave() {
+	c.leaving.Store(true)
+
+	c.connLock.Lock()
+	// unlike WriteMessage, WriteControl can be called concurrently with writeLoop
+	c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(closeWriteTimeout))
 	c.conn.Close()
+	c.connLock.Unlock()
 }
 
 func extractError(response *http.Response, action string) error {
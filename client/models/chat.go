@@ -2,7 +2,10 @@ package models
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
@@ -16,15 +19,37 @@ import (
 var (
 	senderNameStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("13"))
 	systemMessageStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	chatErrorStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	selectedStyle      = lipgloss.NewStyle().Reverse(true)
+	mentionStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
 )
 
+const (
+	// Minimal interval between typing notifications sent to the server.
+	typingInterval = 2 * time.Second
+
+	// Maximal length of quoted parent message text.
+	quoteLength = 40
+)
+
+// Matches input reacting to the selected or the latest message, e.g. "+:thumbsup:"
+// to add reaction or "-:thumbsup:" to remove it.
+var reactionInputRegexp = regexp.MustCompile(`^([+-]):([^:\s]+):$`)
+
 type chatKeys struct {
-	Enter  key.Binding
-	Escape key.Binding
+	Enter      key.Binding
+	Escape     key.Binding
+	Edit       key.Binding
+	Delete     key.Binding
+	SelectPrev key.Binding
+	SelectNext key.Binding
+	Reply      key.Binding
 }
 
-func (k chatKeys) Bindings() []key.Binding {
-	return []key.Binding{k.Enter, k.Escape}
+// Line of chat log: either a message or a notice.
+type chatEntry struct {
+	message *events.NewMessage
+	notice  string
 }
 
 type Chat struct {
@@ -33,10 +58,22 @@ type Chat struct {
 	width  int
 	height int
 
-	messages []string
+	messages []chatEntry
+	members  []string
 	textarea textarea.Model
 	help     help.Model
 
+	// Time until which each user is considered typing.
+	typing         map[string]time.Time
+	lastTypingSent time.Time
+
+	// ID of the message being edited, empty if a new message is typed.
+	editing string
+	// ID of the selected message, empty if none is selected.
+	selected string
+	// ID of the message new one replies to, empty if it's not a reply.
+	replyingTo string
+
 	client *apiclient.ApiClient
 }
 
@@ -51,11 +88,32 @@ func NewChat(width, height int, client *apiclient.ApiClient) Chat {
 				key.WithKeys("esc"),
 				key.WithHelp("esc", "leave"),
 			),
+			Edit: key.NewBinding(
+				key.WithKeys("up"),
+				key.WithHelp("↑", "edit last"),
+			),
+			Delete: key.NewBinding(
+				key.WithKeys("ctrl+d"),
+				key.WithHelp("ctrl+d", "delete"),
+			),
+			SelectPrev: key.NewBinding(
+				key.WithKeys("ctrl+p"),
+				key.WithHelp("ctrl+p/ctrl+n", "select"),
+			),
+			SelectNext: key.NewBinding(
+				key.WithKeys("ctrl+n"),
+			),
+			Reply: key.NewBinding(
+				key.WithKeys("ctrl+r"),
+				key.WithHelp("ctrl+r", "reply"),
+			),
 		},
 
 		textarea: textarea.New(),
 		help:     help.New(),
 
+		typing: make(map[string]time.Time),
+
 		client: client,
 	}
 
@@ -79,21 +137,91 @@ func (m Chat) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.setSize(msg.Width, msg.Height)
 
 	case EventMsg:
-		line := ""
+		notice := ""
 		switch event := msg.Event.(type) {
 		case *events.NewMessage:
-			line = fmt.Sprintf(
-				"%s: %s", senderNameStyle.Render(event.Producer), event.Text)
+			m.messages = append(m.messages, chatEntry{message: event})
+			delete(m.typing, event.Producer)
+		case *events.MessageEdited:
+			if message := m.findMessage(event.ID); message != nil {
+				message.Text = event.Text
+				message.Edited = true
+			}
+		case *events.MessageDeleted:
+			if message := m.findMessage(event.ID); message != nil {
+				message.Text = ""
+				message.Deleted = true
+			}
+			if m.editing == event.ID {
+				m.stopEditing()
+			}
+			if m.selected == event.ID {
+				m.selected = ""
+			}
+			if m.replyingTo == event.ID {
+				m.replyingTo = ""
+			}
+		case *events.ReactionsUpdated:
+			if message := m.findMessage(event.MessageID); message != nil {
+				message.Reactions = event.Reactions
+			}
+		case *events.Mentioned:
+			// mentions in this chat are highlighted, others are shown as notices
+			if m.findMessage(event.MessageID) == nil {
+				notice = mentionStyle.Render(fmt.Sprintf(
+					"%s mentioned you in %s: %s", event.Producer, event.Chat, event.Text))
+			}
+		case *events.Error:
+			notice = chatErrorStyle.Render(event.Text)
+		case *events.Typing:
+			timeout := event.Until.Sub(event.Time) // not relying on clocks being in sync
+			m.typing[event.Producer] = time.Now().Add(timeout)
+			return m, tea.Batch(
+				readEventCmd(m.client),
+				tea.Tick(timeout, func(time.Time) tea.Msg { return typingExpiredMsg{} }))
 		case *events.SystemMessage:
-			line = systemMessageStyle.Render(event.Text)
+			notice = systemMessageStyle.Render(event.Text)
+		case *events.Members:
+			m.members = event.Usernames
+		case *events.MemberJoined:
+			m.members = addMember(m.members, event.Username)
+			notice = systemMessageStyle.Render(fmt.Sprintf("%s joined chat", event.Username))
+		case *events.MemberLeft:
+			m.members = removeMember(m.members, event.Username)
+			notice = systemMessageStyle.Render(fmt.Sprintf("%s left chat", event.Username))
+		case *events.MemberKicked:
+			notice = systemMessageStyle.Render(fmt.Sprintf(
+				"%s was kicked by %s", event.Username, event.KickedBy))
+			if event.Username == m.client.Username() {
+				notice = chatErrorStyle.Render(fmt.Sprintf(
+					"you were kicked by %s, press esc to leave", event.KickedBy))
+			}
+		case *events.MemberMuted:
+			notice = systemMessageStyle.Render(fmt.Sprintf("%s was muted by %s for %v",
+				event.Username, event.MutedBy, event.Until.Sub(event.Time).Round(time.Second)))
+		case *events.TopicChanged:
+			notice = systemMessageStyle.Render(fmt.Sprintf(
+				"%s changed topic to: %s", event.Producer, event.Topic))
+			if event.Topic == "" {
+				notice = systemMessageStyle.Render(fmt.Sprintf("%s cleared topic", event.Producer))
+			}
 		}
 
-		if line != "" {
-			m.messages = append(m.messages, line)
+		if notice != "" {
+			m.messages = append(m.messages, chatEntry{notice: notice})
 		}
 
 		return m, readEventCmd(m.client)
 
+	case typingExpiredMsg:
+		now := time.Now()
+		for username, until := range m.typing {
+			if !until.After(now) {
+				delete(m.typing, username)
+			}
+		}
+		return m, nil
+
 	case ChatConnClosedMsg:
 		return m, func() tea.Msg { return BackToHubMsg{} }
 
@@ -104,15 +232,50 @@ func (m Chat) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if message == "" {
 				return m, nil
 			}
+			var event any = &events.NewMessage{Text: message, ReplyTo: m.replyingTo}
+			if reaction := m.reactionEvent(message); reaction != nil {
+				event = reaction
+			} else if m.editing != "" {
+				event = &events.EditMessage{ID: m.editing, Text: message}
+				m.stopEditing()
+			}
+			m.selected = ""
+			m.replyingTo = ""
 			m.textarea.Reset()
-			return m, func() tea.Msg {
-				err := m.client.WriteEvent(&events.NewMessage{Text: message})
-				if err != nil {
-					return ErrorMsg(err.Error())
-				}
-				return nil
+			m.lastTypingSent = time.Time{}
+			return m, m.writeEventCmd(event)
+		case m.editing == "" && key.Matches(msg, m.keys.Edit) && m.textarea.Value() == "":
+			if message := m.lastOwnMessage(); message != nil {
+				m.editing = message.ID
+				m.textarea.SetValue(message.Text)
 			}
+			return m, nil
+		case m.editing != "" && key.Matches(msg, m.keys.Delete):
+			event := &events.DeleteMessage{ID: m.editing}
+			m.stopEditing()
+			return m, m.writeEventCmd(event)
+		case m.editing == "" && key.Matches(msg, m.keys.SelectPrev):
+			m.moveSelection(-1)
+			return m, nil
+		case m.editing == "" && key.Matches(msg, m.keys.SelectNext):
+			m.moveSelection(1)
+			return m, nil
+		case m.selected != "" && key.Matches(msg, m.keys.Reply):
+			m.replyingTo = m.selected
+			m.selected = ""
+			return m, nil
 		case key.Matches(msg, m.keys.Escape):
+			switch {
+			case m.editing != "":
+				m.stopEditing()
+				return m, nil
+			case m.replyingTo != "":
+				m.replyingTo = ""
+				return m, nil
+			case m.selected != "":
+				m.selected = ""
+				return m, nil
+			}
 			m.client.Leave()
 			return m, func() tea.Msg { return BackToHubMsg{} }
 		}
@@ -123,23 +286,203 @@ func (m Chat) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		vpCmd tea.Cmd
 	)
 
+	value := m.textarea.Value()
 	m.textarea, tiCmd = m.textarea.Update(msg)
+	typingCmd := m.typingCmd(value)
+
+	return m, tea.Batch(tiCmd, vpCmd, typingCmd)
+}
+
+// Notifies other members that user is typing if textarea value was changed,
+// but not more often than typingInterval.
+func (m *Chat) typingCmd(previousValue string) tea.Cmd {
+	value := m.textarea.Value()
+	if m.editing != "" || value == previousValue || value == "" ||
+		time.Since(m.lastTypingSent) < typingInterval {
+		return nil
+	}
+
+	m.lastTypingSent = time.Now()
+	return m.writeEventCmd(&events.Typing{})
+}
+
+func (m Chat) writeEventCmd(event any) tea.Cmd {
+	return func() tea.Msg {
+		err := m.client.WriteEvent(event)
+		if err != nil {
+			return ErrorMsg(err.Error())
+		}
+		return nil
+	}
+}
+
+func (m *Chat) stopEditing() {
+	m.editing = ""
+	m.textarea.Reset()
+}
+
+// Finds received message by its ID, returns nil if it's not found.
+func (m Chat) findMessage(id string) *events.NewMessage {
+	if i := m.messageIndex(id); i >= 0 {
+		return m.messages[i].message
+	}
+
+	return nil
+}
+
+// Finds index of received message by its ID, returns -1 if it's not found.
+func (m Chat) messageIndex(id string) int {
+	if id == "" {
+		return -1
+	}
+
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if message := m.messages[i].message; message != nil && message.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Selects the previous (negative step) or the next (positive step) not deleted message.
+// Selection starts from the latest message and is cleared once moved past it.
+func (m *Chat) moveSelection(step int) {
+	i := len(m.messages)
+	if m.selected != "" {
+		i = m.messageIndex(m.selected)
+	} else if step > 0 {
+		return
+	}
+
+	for i += step; i >= 0 && i < len(m.messages); i += step {
+		if message := m.messages[i].message; message != nil && !message.Deleted {
+			m.selected = message.ID
+			return
+		}
+	}
+
+	if step > 0 {
+		m.selected = ""
+	}
+}
+
+// Builds reaction event to the selected or the latest message if input is
+// a reaction, returns nil otherwise.
+func (m Chat) reactionEvent(input string) any {
+	match := reactionInputRegexp.FindStringSubmatch(input)
+	if match == nil || m.editing != "" {
+		return nil
+	}
+
+	target := m.findMessage(m.selected)
+	for i := len(m.messages) - 1; i >= 0 && target == nil; i-- {
+		if message := m.messages[i].message; message != nil && !message.Deleted {
+			target = message
+		}
+	}
+	if target == nil {
+		return nil
+	}
+
+	if match[1] == "-" {
+		return &events.RemoveReaction{MessageID: target.ID, Emoji: match[2]}
+	}
+	return &events.AddReaction{MessageID: target.ID, Emoji: match[2]}
+}
+
+// Finds the latest not deleted message sent by user, returns nil if there is none.
+func (m Chat) lastOwnMessage() *events.NewMessage {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		message := m.messages[i].message
+		if message != nil && !message.Deleted && message.Producer == m.client.Username() {
+			return message
+		}
+	}
 
-	return m, tea.Batch(tiCmd, vpCmd)
+	return nil
 }
 
 func (m Chat) View() string {
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
+		systemMessageStyle.
+			Width(m.width).
+			MaxHeight(1).
+			Render(fmt.Sprintf("%d online: %s", len(m.members), strings.Join(m.members, ", "))),
 		lipgloss.NewStyle().
 			Width(m.width).
-			Height(m.height-m.textarea.Height()-1).
-			Render(strings.Join(m.messages, "\n")), // TODO find some better way to display messages; bubbles.viewport seems almost ideal but it crops long messages instead of wrapping them
+			Height(m.height-m.textarea.Height()-3).
+			Render(m.messagesView()), // TODO find some better way to display messages; bubbles.viewport seems almost ideal but it crops long messages instead of wrapping them
+		systemMessageStyle.
+			Width(m.width).
+			MaxHeight(1).
+			Render(m.statusLine()),
 		m.textarea.View(),
-		m.help.ShortHelpView(m.keys.Bindings()),
+		m.help.ShortHelpView(m.bindings()),
 	)
 }
 
+// Gets bindings relevant to the current mode.
+func (m Chat) bindings() []key.Binding {
+	cancelBinding := m.keys.Escape
+	cancelBinding.SetHelp("esc", "cancel")
+
+	switch {
+	case m.editing != "":
+		saveBinding := m.keys.Enter
+		saveBinding.SetHelp("enter", "save")
+		return []key.Binding{saveBinding, m.keys.Delete, cancelBinding}
+	case m.selected != "":
+		return []key.Binding{m.keys.Reply, m.keys.SelectPrev, cancelBinding}
+	case m.replyingTo != "":
+		return []key.Binding{m.keys.Enter, cancelBinding}
+	default:
+		return []key.Binding{m.keys.Enter, m.keys.Edit, m.keys.SelectPrev, m.keys.Escape}
+	}
+}
+
+func (m Chat) messagesView() string {
+	lines := make([]string, len(m.messages))
+	for i, entry := range m.messages {
+		message := entry.message
+		if message != nil && message.ReplyTo != "" {
+			lines[i] = m.quoteView(message.ReplyTo) + "\n"
+		}
+
+		switch {
+		case message == nil:
+			lines[i] = entry.notice
+		case message.Deleted:
+			lines[i] += fmt.Sprintf("%s: %s",
+				senderNameStyle.Render(message.Producer), systemMessageStyle.Render("message deleted"))
+		case message.Action:
+			lines[i] += fmt.Sprintf("* %s %s", senderNameStyle.Render(message.Producer), message.Text)
+			if message.Edited {
+				lines[i] += " " + systemMessageStyle.Render("(edited)")
+			}
+		case message.Edited:
+			lines[i] += fmt.Sprintf("%s: %s %s",
+				senderNameStyle.Render(message.Producer), message.Text, systemMessageStyle.Render("(edited)"))
+		default:
+			lines[i] += fmt.Sprintf("%s: %s", senderNameStyle.Render(message.Producer), message.Text)
+		}
+
+		switch {
+		case message != nil && message.ID == m.selected:
+			lines[i] = selectedStyle.Render(lines[i])
+		case message != nil && !message.Deleted && m.mentionsUser(message):
+			lines[i] = mentionStyle.Render(lines[i])
+		}
+
+		if message != nil && len(message.Reactions) > 0 && !message.Deleted {
+			lines[i] += "\n" + reactionsView(message.Reactions)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
 func (m *Chat) setSize(width, height int) {
 	m.width = width
 	m.height = height
@@ -147,12 +490,109 @@ func (m *Chat) setSize(width, height int) {
 	m.textarea.SetWidth(width)
 }
 
+// Checks whether message mentions the logged in user.
+func (m Chat) mentionsUser(message *events.NewMessage) bool {
+	for _, username := range message.Mentions {
+		if username == m.client.Username() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Renders quoted snippet of the parent message.
+func (m Chat) quoteView(parentID string) string {
+	return systemMessageStyle.Render("┌ " + m.quote(parentID))
+}
+
+// Gets snippet of the parent message.
+func (m Chat) quote(parentID string) string {
+	parent := m.findMessage(parentID)
+	switch {
+	case parent == nil:
+		return "an earlier message"
+	case parent.Deleted:
+		return "a deleted message"
+	}
+
+	text := []rune(parent.Text)
+	if len(text) > quoteLength {
+		text = append(text[:quoteLength-1], '…')
+	}
+
+	return fmt.Sprintf("%s: %s", parent.Producer, string(text))
+}
+
+// Shows which message is replied to, otherwise who is typing.
+func (m Chat) statusLine() string {
+	if m.replyingTo != "" {
+		return "replying to " + m.quote(m.replyingTo)
+	}
+
+	return m.typingLine()
+}
+
+func (m Chat) typingLine() string {
+	now := time.Now()
+	typing := []string{}
+	for username, until := range m.typing {
+		if until.After(now) {
+			typing = append(typing, username)
+		}
+	}
+	sort.Strings(typing)
+
+	switch len(typing) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("%s is typing...", typing[0])
+	default:
+		return fmt.Sprintf("%s are typing...", strings.Join(typing, ", "))
+	}
+}
+
+func reactionsView(reactions []events.ReactionCount) string {
+	counts := make([]string, len(reactions))
+	for i, reaction := range reactions {
+		counts[i] = fmt.Sprintf(":%s: %d", reaction.Emoji, reaction.Count)
+	}
+
+	return systemMessageStyle.Render("  " + strings.Join(counts, "  "))
+}
+
+// Adds username to sorted members list unless it's already there.
+func addMember(members []string, username string) []string {
+	i := sort.SearchStrings(members, username)
+	if i < len(members) && members[i] == username {
+		return members
+	}
+
+	members = append(members, "")
+	copy(members[i+1:], members[i:])
+	members[i] = username
+
+	return members
+}
+
+func removeMember(members []string, username string) []string {
+	i := sort.SearchStrings(members, username)
+	if i == len(members) || members[i] != username {
+		return members
+	}
+
+	return append(members[:i], members[i+1:]...)
+}
+
 type EventMsg struct {
 	Event any
 }
 
 type ChatConnClosedMsg struct{}
 
+type typingExpiredMsg struct{}
+
 func readEventCmd(client *apiclient.ApiClient) tea.Cmd {
 	return func() tea.Msg {
 		event, more, err := client.ReadEvent()
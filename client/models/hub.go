@@ -3,6 +3,7 @@ package models
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
@@ -11,6 +12,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/shkotk/gochat/client/apiclient"
+	"github.com/shkotk/gochat/common/apimodels/responses"
 )
 
 type hubKeys struct {
@@ -18,6 +20,8 @@ type hubKeys struct {
 	Enter   key.Binding
 	Refresh key.Binding
 	Escape  key.Binding
+	Inbox   key.Binding
+	Clear   key.Binding
 }
 
 type hubState int
@@ -25,6 +29,7 @@ type hubState int
 const (
 	chatsList hubState = iota
 	createChatMenu
+	inbox
 )
 
 type Hub struct {
@@ -40,6 +45,9 @@ type Hub struct {
 	err   string
 	help  help.Model
 
+	notifications []responses.Notification
+	unread        int64
+
 	client *apiclient.ApiClient
 }
 
@@ -62,6 +70,14 @@ func NewHub(width, height int, client *apiclient.ApiClient) tea.Model {
 				key.WithKeys("esc"),
 				key.WithHelp("esc", "cancel"),
 			),
+			Inbox: key.NewBinding(
+				key.WithKeys("ctrl+o"),
+				key.WithHelp("ctrl+o", "inbox"),
+			),
+			Clear: key.NewBinding(
+				key.WithKeys("ctrl+x"),
+				key.WithHelp("ctrl+x", "clear"),
+			),
 		},
 
 		input: textinput.New(),
@@ -88,7 +104,7 @@ func NewHub(width, height int, client *apiclient.ApiClient) tea.Model {
 }
 
 func (m Hub) Init() tea.Cmd {
-	return fetchChatsCmd(m.client)
+	return tea.Batch(fetchChatsCmd(m.client), fetchNotificationsCmd(m.client))
 }
 
 func (m Hub) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -107,6 +123,33 @@ func (m Hub) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmd := m.list.SetItems(items)
 		return m, cmd
 
+	case notificationsMsg:
+		m.notifications = msg.Notifications
+		m.setUnread(msg.Unread)
+		// only listed notifications are marked read, once they're shown as unread
+		if m.state != inbox {
+			return m, nil
+		}
+		ids := []string{}
+		for _, notification := range msg.Notifications {
+			if !notification.Read {
+				ids = append(ids, notification.ID)
+			}
+		}
+		if len(ids) == 0 {
+			return m, nil
+		}
+		return m, markNotificationsReadCmd(m.client, ids...)
+
+	case notificationsReadMsg:
+		m.setUnread(m.unread - msg.Count)
+		return m, nil
+
+	case notificationsClearedMsg:
+		m.notifications = nil
+		m.setUnread(0)
+		return m, nil
+
 	case chatCreatedMsg:
 		m.state = chatsList
 		m.input.Reset()
@@ -118,6 +161,16 @@ func (m Hub) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			break // let list handle all key presses while setting a filter
 		}
 
+		if m.state == inbox {
+			switch {
+			case key.Matches(msg, m.keys.Clear):
+				return m, clearNotificationsCmd(m.client)
+			case key.Matches(msg, m.keys.Escape):
+				m.state = chatsList
+			}
+			return m, nil
+		}
+
 		if key.Matches(msg, m.keys.Create, m.keys.Enter) {
 			m.err = "" // reset error on changing focus
 		}
@@ -128,7 +181,10 @@ func (m Hub) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmd := m.input.Focus()
 			return m, cmd
 		case key.Matches(msg, m.keys.Refresh):
-			return m, fetchChatsCmd(m.client)
+			return m, tea.Batch(fetchChatsCmd(m.client), fetchNotificationsCmd(m.client))
+		case m.state == chatsList && key.Matches(msg, m.keys.Inbox):
+			m.state = inbox
+			return m, fetchNotificationsCmd(m.client)
 		case key.Matches(msg, m.keys.Enter):
 			switch m.state {
 			case chatsList:
@@ -166,6 +222,7 @@ func (m Hub) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.list, cmd = m.list.Update(msg)
 	case createChatMenu:
 		m.input, cmd = m.input.Update(msg)
+	case inbox:
 	default:
 		log.Panicf("unexpected state %v", m.state)
 	}
@@ -186,7 +243,7 @@ func (m Hub) View() string {
 			joinBinding.SetHelp("enter", "join")
 			additionalKeys = func() []key.Binding {
 				return []key.Binding{
-					m.keys.Create, joinBinding, m.keys.Refresh,
+					m.keys.Create, joinBinding, m.keys.Refresh, m.keys.Inbox,
 				}
 			}
 		}
@@ -214,11 +271,60 @@ func (m Hub) View() string {
 			m.help.ShortHelpView([]key.Binding{createBinding, m.keys.Escape}),
 		)
 
+	case inbox:
+		backBinding := m.keys.Escape
+		backBinding.SetHelp("esc", "back")
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			lipgloss.NewStyle().
+				Width(m.width).
+				Height(m.height-1). // one line for help
+				Render(m.inboxView()),
+			m.help.ShortHelpView([]key.Binding{m.keys.Clear, backBinding}),
+		)
+
 	default:
 		panic(fmt.Sprintf("unexpected state %v", m.state))
 	}
 }
 
+func (m Hub) inboxView() string {
+	if len(m.notifications) == 0 {
+		return systemMessageStyle.Render("No notifications")
+	}
+
+	lines := make([]string, len(m.notifications))
+	for i, notification := range m.notifications {
+		marker := " "
+		if !notification.Read {
+			marker = "•"
+		}
+
+		action := fmt.Sprintf("mentioned you in %s", notification.ChatName)
+		if notification.Kind == "direct" {
+			action = "sent you a direct message"
+		}
+
+		lines[i] = fmt.Sprintf("%s %s %s %s: %s",
+			marker,
+			systemMessageStyle.Render(notification.Time.Local().Format("Jan 2 15:04")),
+			senderNameStyle.Render(notification.Producer),
+			action,
+			notification.Text)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Updates unread notifications counter shown as a badge in list title.
+func (m *Hub) setUnread(unread int64) {
+	m.unread = unread
+	m.list.Title = "Chats"
+	if unread > 0 {
+		m.list.Title = fmt.Sprintf("Chats (%d unread)", unread)
+	}
+}
+
 func (m *Hub) setSize(width, height int) {
 	m.width = width
 	m.height = height
@@ -249,6 +355,53 @@ func fetchChatsCmd(client *apiclient.ApiClient) tea.Cmd {
 	}
 }
 
+type notificationsMsg struct {
+	Notifications []responses.Notification
+	Unread        int64
+}
+
+func fetchNotificationsCmd(client *apiclient.ApiClient) tea.Cmd {
+	return func() tea.Msg {
+		notifications, err := client.GetNotifications()
+		if err != nil {
+			return ErrorMsg(err.Error())
+		}
+
+		return notificationsMsg{
+			Notifications: notifications.Notifications,
+			Unread:        notifications.Unread,
+		}
+	}
+}
+
+type notificationsReadMsg struct {
+	Count int64
+}
+
+func markNotificationsReadCmd(client *apiclient.ApiClient, ids ...string) tea.Cmd {
+	return func() tea.Msg {
+		err := client.MarkNotificationsRead(ids...)
+		if err != nil {
+			return ErrorMsg(err.Error())
+		}
+
+		return notificationsReadMsg{Count: int64(len(ids))}
+	}
+}
+
+type notificationsClearedMsg struct{}
+
+func clearNotificationsCmd(client *apiclient.ApiClient) tea.Cmd {
+	return func() tea.Msg {
+		err := client.ClearNotifications()
+		if err != nil {
+			return ErrorMsg(err.Error())
+		}
+
+		return notificationsClearedMsg{}
+	}
+}
+
 type chatCreatedMsg struct{}
 
 func createChatCmd(client *apiclient.ApiClient, chatName string) tea.Cmd {
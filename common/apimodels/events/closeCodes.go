@@ -0,0 +1,10 @@
+package events
+
+// Application WebSocket close codes (RFC 6455 reserves 4000-4999 for private use)
+// server closes connection with when reconnecting won't help.
+const (
+	// Client was kicked from chat by moderator.
+	CloseKicked = 4000
+	// Client was disconnected for sending too many events.
+	CloseFlooding = 4001
+)
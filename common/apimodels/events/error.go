@@ -0,0 +1,16 @@
+package events
+
+import "time"
+
+// Notifies client that its request or event was rejected.
+// Chat is empty if error is not related to a specific chat.
+type Error struct {
+	Chat string
+	Text string
+	Time time.Time
+}
+
+func (e Error) GetChat() string         { return e.Chat }
+func (e *Error) SetChat(chat string)    { e.Chat = chat }
+func (e Error) GetTime() time.Time      { return e.Time }
+func (e *Error) SetTime(time time.Time) { e.Time = time }
@@ -11,3 +11,9 @@ type Timed interface {
 	GetTime() time.Time
 	SetTime(time.Time)
 }
+
+// Event which belongs to a specific chat.
+type Routed interface {
+	GetChat() string
+	SetChat(string)
+}
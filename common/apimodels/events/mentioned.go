@@ -0,0 +1,18 @@
+package events
+
+import "time"
+
+// Notifies user that they were mentioned in a message, regardless of chats
+// they are connected to.
+type Mentioned struct {
+	MessageID string
+	Chat      string
+	Producer  string
+	Time      time.Time
+	Text      string
+}
+
+func (m Mentioned) GetChat() string         { return m.Chat }
+func (m *Mentioned) SetChat(chat string)    { m.Chat = chat }
+func (m Mentioned) GetTime() time.Time      { return m.Time }
+func (m *Mentioned) SetTime(time time.Time) { m.Time = time }
@@ -0,0 +1,63 @@
+package events
+
+import "time"
+
+// Requests to replace text of the message with provided ID.
+// Only message producer or chat moderator can edit it.
+type EditMessage struct {
+	ID       string
+	Chat     string
+	Producer string
+	Time     time.Time
+	Text     string
+}
+
+func (m EditMessage) GetChat() string              { return m.Chat }
+func (m *EditMessage) SetChat(chat string)         { m.Chat = chat }
+func (m EditMessage) GetProducer() string          { return m.Producer }
+func (m *EditMessage) SetProducer(producer string) { m.Producer = producer }
+func (m EditMessage) GetTime() time.Time           { return m.Time }
+func (m *EditMessage) SetTime(time time.Time)      { m.Time = time }
+
+// Requests to delete the message with provided ID.
+// Only message producer or chat moderator can delete it.
+type DeleteMessage struct {
+	ID       string
+	Chat     string
+	Producer string
+	Time     time.Time
+}
+
+func (m DeleteMessage) GetChat() string              { return m.Chat }
+func (m *DeleteMessage) SetChat(chat string)         { m.Chat = chat }
+func (m DeleteMessage) GetProducer() string          { return m.Producer }
+func (m *DeleteMessage) SetProducer(producer string) { m.Producer = producer }
+func (m DeleteMessage) GetTime() time.Time           { return m.Time }
+func (m *DeleteMessage) SetTime(time time.Time)      { m.Time = time }
+
+// Notifies that text of the message with provided ID was replaced.
+type MessageEdited struct {
+	ID       string
+	Chat     string
+	EditedBy string
+	Time     time.Time
+	Text     string
+}
+
+func (m MessageEdited) GetChat() string         { return m.Chat }
+func (m *MessageEdited) SetChat(chat string)    { m.Chat = chat }
+func (m MessageEdited) GetTime() time.Time      { return m.Time }
+func (m *MessageEdited) SetTime(time time.Time) { m.Time = time }
+
+// Notifies that the message with provided ID was deleted.
+type MessageDeleted struct {
+	ID        string
+	Chat      string
+	DeletedBy string
+	Time      time.Time
+}
+
+func (m MessageDeleted) GetChat() string         { return m.Chat }
+func (m *MessageDeleted) SetChat(chat string)    { m.Chat = chat }
+func (m MessageDeleted) GetTime() time.Time      { return m.Time }
+func (m *MessageDeleted) SetTime(time time.Time) { m.Time = time }
@@ -0,0 +1,45 @@
+package events
+
+import "time"
+
+// Notifies that user was removed from the chat by a moderator.
+type MemberKicked struct {
+	Chat     string
+	Username string
+	KickedBy string
+	Time     time.Time
+}
+
+func (m MemberKicked) GetChat() string         { return m.Chat }
+func (m *MemberKicked) SetChat(chat string)    { m.Chat = chat }
+func (m MemberKicked) GetTime() time.Time      { return m.Time }
+func (m *MemberKicked) SetTime(time time.Time) { m.Time = time }
+
+// Notifies that user can't send messages to the chat until provided time.
+type MemberMuted struct {
+	Chat     string
+	Username string
+	MutedBy  string
+	Time     time.Time
+	Until    time.Time
+}
+
+func (m MemberMuted) GetChat() string         { return m.Chat }
+func (m *MemberMuted) SetChat(chat string)    { m.Chat = chat }
+func (m MemberMuted) GetTime() time.Time      { return m.Time }
+func (m *MemberMuted) SetTime(time time.Time) { m.Time = time }
+
+// Notifies that chat topic was changed, empty topic means it was cleared.
+type TopicChanged struct {
+	Chat     string
+	Producer string
+	Time     time.Time
+	Topic    string
+}
+
+func (t TopicChanged) GetChat() string              { return t.Chat }
+func (t *TopicChanged) SetChat(chat string)         { t.Chat = chat }
+func (t TopicChanged) GetProducer() string          { return t.Producer }
+func (t *TopicChanged) SetProducer(producer string) { t.Producer = producer }
+func (t TopicChanged) GetTime() time.Time           { return t.Time }
+func (t *TopicChanged) SetTime(time time.Time)      { t.Time = time }
@@ -2,12 +2,31 @@ package events
 
 import "time"
 
+// Message posted to the chat. ID and Seq are assigned by server once message is
+// stored, Seq numbers messages of a chat in order they're delivered. ReplyTo is ID
+// of the message of the same chat this one replies to, if any. Mentions lists
+// existing users mentioned in Text as @username, set by server. Action is set by
+// server for messages describing producer's action, posted with /me. Edited and
+// Deleted are set on replayed messages which were changed after being posted,
+// Reactions are set on replayed messages which have any.
 type NewMessage struct {
+	ID       string
+	Seq      uint64
+	Chat     string
 	Producer string
 	Time     time.Time
 	Text     string
+	ReplyTo  string
+	Mentions []string
+	Action   bool
+	Edited   bool
+	Deleted  bool
+
+	Reactions []ReactionCount
 }
 
+func (m NewMessage) GetChat() string              { return m.Chat }
+func (m *NewMessage) SetChat(chat string)         { m.Chat = chat }
 func (m NewMessage) GetProducer() string          { return m.Producer }
 func (m *NewMessage) SetProducer(producer string) { m.Producer = producer }
 func (m NewMessage) GetTime() time.Time           { return m.Time }
@@ -0,0 +1,39 @@
+package events
+
+import "time"
+
+// Notifies that user joined the chat.
+type MemberJoined struct {
+	Chat     string
+	Username string
+	Time     time.Time
+}
+
+func (m MemberJoined) GetChat() string         { return m.Chat }
+func (m *MemberJoined) SetChat(chat string)    { m.Chat = chat }
+func (m MemberJoined) GetTime() time.Time      { return m.Time }
+func (m *MemberJoined) SetTime(time time.Time) { m.Time = time }
+
+// Notifies that user left the chat.
+type MemberLeft struct {
+	Chat     string
+	Username string
+	Time     time.Time
+}
+
+func (m MemberLeft) GetChat() string         { return m.Chat }
+func (m *MemberLeft) SetChat(chat string)    { m.Chat = chat }
+func (m MemberLeft) GetTime() time.Time      { return m.Time }
+func (m *MemberLeft) SetTime(time time.Time) { m.Time = time }
+
+// Snapshot of users currently in the chat, sent to client upon joining.
+type Members struct {
+	Chat      string
+	Usernames []string
+	Time      time.Time
+}
+
+func (m Members) GetChat() string         { return m.Chat }
+func (m *Members) SetChat(chat string)    { m.Chat = chat }
+func (m Members) GetTime() time.Time      { return m.Time }
+func (m *Members) SetTime(time time.Time) { m.Time = time }
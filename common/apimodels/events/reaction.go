@@ -0,0 +1,56 @@
+package events
+
+import "time"
+
+// Requests to react to the message with provided ID. Emoji is a shortcode
+// without colons, e.g. "thumbsup".
+type AddReaction struct {
+	MessageID string
+	Chat      string
+	Producer  string
+	Time      time.Time
+	Emoji     string
+}
+
+func (r AddReaction) GetChat() string              { return r.Chat }
+func (r *AddReaction) SetChat(chat string)         { r.Chat = chat }
+func (r AddReaction) GetProducer() string          { return r.Producer }
+func (r *AddReaction) SetProducer(producer string) { r.Producer = producer }
+func (r AddReaction) GetTime() time.Time           { return r.Time }
+func (r *AddReaction) SetTime(time time.Time)      { r.Time = time }
+
+// Requests to withdraw reaction added to the message with provided ID.
+type RemoveReaction struct {
+	MessageID string
+	Chat      string
+	Producer  string
+	Time      time.Time
+	Emoji     string
+}
+
+func (r RemoveReaction) GetChat() string              { return r.Chat }
+func (r *RemoveReaction) SetChat(chat string)         { r.Chat = chat }
+func (r RemoveReaction) GetProducer() string          { return r.Producer }
+func (r *RemoveReaction) SetProducer(producer string) { r.Producer = producer }
+func (r RemoveReaction) GetTime() time.Time           { return r.Time }
+func (r *RemoveReaction) SetTime(time time.Time)      { r.Time = time }
+
+// Number of users who reacted to a message with the same emoji.
+type ReactionCount struct {
+	Emoji string
+	Count int
+}
+
+// Notifies about current reactions to the message with provided ID,
+// sent whenever a reaction is added or removed.
+type ReactionsUpdated struct {
+	MessageID string
+	Chat      string
+	Time      time.Time
+	Reactions []ReactionCount
+}
+
+func (r ReactionsUpdated) GetChat() string         { return r.Chat }
+func (r *ReactionsUpdated) SetChat(chat string)    { r.Chat = chat }
+func (r ReactionsUpdated) GetTime() time.Time      { return r.Time }
+func (r *ReactionsUpdated) SetTime(time time.Time) { r.Time = time }
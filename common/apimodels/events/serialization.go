@@ -8,8 +8,27 @@ import (
 )
 
 var (
-	newMessagePrefix    = []byte("NewMessage|")
-	systemMessagePrefix = []byte("SystemMessage|")
+	newMessagePrefix       = []byte("NewMessage|")
+	systemMessagePrefix    = []byte("SystemMessage|")
+	subscribePrefix        = []byte("Subscribe|")
+	unsubscribePrefix      = []byte("Unsubscribe|")
+	errorPrefix            = []byte("Error|")
+	memberJoinedPrefix     = []byte("MemberJoined|")
+	memberLeftPrefix       = []byte("MemberLeft|")
+	membersPrefix          = []byte("Members|")
+	typingPrefix           = []byte("Typing|")
+	editMessagePrefix      = []byte("EditMessage|")
+	deleteMessagePrefix    = []byte("DeleteMessage|")
+	messageEditedPrefix    = []byte("MessageEdited|")
+	messageDeletedPrefix   = []byte("MessageDeleted|")
+	addReactionPrefix      = []byte("AddReaction|")
+	removeReactionPrefix   = []byte("RemoveReaction|")
+	reactionsUpdatedPrefix = []byte("ReactionsUpdated|")
+	mentionedPrefix        = []byte("Mentioned|")
+	memberKickedPrefix     = []byte("MemberKicked|")
+	memberMutedPrefix      = []byte("MemberMuted|")
+	topicChangedPrefix     = []byte("TopicChanged|")
+	replayTruncatedPrefix  = []byte("ReplayTruncated|")
 )
 
 // Serializes event to a JSON string with prefix representing event type.
@@ -21,6 +40,44 @@ func Serialize(event any) ([]byte, error) {
 		prefix = newMessagePrefix
 	case *SystemMessage:
 		prefix = systemMessagePrefix
+	case *Subscribe:
+		prefix = subscribePrefix
+	case *Unsubscribe:
+		prefix = unsubscribePrefix
+	case *Error:
+		prefix = errorPrefix
+	case *MemberJoined:
+		prefix = memberJoinedPrefix
+	case *MemberLeft:
+		prefix = memberLeftPrefix
+	case *Members:
+		prefix = membersPrefix
+	case *Typing:
+		prefix = typingPrefix
+	case *EditMessage:
+		prefix = editMessagePrefix
+	case *DeleteMessage:
+		prefix = deleteMessagePrefix
+	case *MessageEdited:
+		prefix = messageEditedPrefix
+	case *MessageDeleted:
+		prefix = messageDeletedPrefix
+	case *AddReaction:
+		prefix = addReactionPrefix
+	case *RemoveReaction:
+		prefix = removeReactionPrefix
+	case *ReactionsUpdated:
+		prefix = reactionsUpdatedPrefix
+	case *Mentioned:
+		prefix = mentionedPrefix
+	case *MemberKicked:
+		prefix = memberKickedPrefix
+	case *MemberMuted:
+		prefix = memberMutedPrefix
+	case *TopicChanged:
+		prefix = topicChangedPrefix
+	case *ReplayTruncated:
+		prefix = replayTruncatedPrefix
 	default:
 		return nil, fmt.Errorf("unknown event type '%T'", event)
 	}
@@ -51,6 +108,44 @@ func Parse(messageBytes []byte) (any, error) {
 		event, err = unmarshal[NewMessage](jsonBytes)
 	case bytes.Equal(prefix, systemMessagePrefix):
 		event, err = unmarshal[SystemMessage](jsonBytes)
+	case bytes.Equal(prefix, subscribePrefix):
+		event, err = unmarshal[Subscribe](jsonBytes)
+	case bytes.Equal(prefix, unsubscribePrefix):
+		event, err = unmarshal[Unsubscribe](jsonBytes)
+	case bytes.Equal(prefix, errorPrefix):
+		event, err = unmarshal[Error](jsonBytes)
+	case bytes.Equal(prefix, memberJoinedPrefix):
+		event, err = unmarshal[MemberJoined](jsonBytes)
+	case bytes.Equal(prefix, memberLeftPrefix):
+		event, err = unmarshal[MemberLeft](jsonBytes)
+	case bytes.Equal(prefix, membersPrefix):
+		event, err = unmarshal[Members](jsonBytes)
+	case bytes.Equal(prefix, typingPrefix):
+		event, err = unmarshal[Typing](jsonBytes)
+	case bytes.Equal(prefix, editMessagePrefix):
+		event, err = unmarshal[EditMessage](jsonBytes)
+	case bytes.Equal(prefix, deleteMessagePrefix):
+		event, err = unmarshal[DeleteMessage](jsonBytes)
+	case bytes.Equal(prefix, messageEditedPrefix):
+		event, err = unmarshal[MessageEdited](jsonBytes)
+	case bytes.Equal(prefix, messageDeletedPrefix):
+		event, err = unmarshal[MessageDeleted](jsonBytes)
+	case bytes.Equal(prefix, addReactionPrefix):
+		event, err = unmarshal[AddReaction](jsonBytes)
+	case bytes.Equal(prefix, removeReactionPrefix):
+		event, err = unmarshal[RemoveReaction](jsonBytes)
+	case bytes.Equal(prefix, reactionsUpdatedPrefix):
+		event, err = unmarshal[ReactionsUpdated](jsonBytes)
+	case bytes.Equal(prefix, mentionedPrefix):
+		event, err = unmarshal[Mentioned](jsonBytes)
+	case bytes.Equal(prefix, memberKickedPrefix):
+		event, err = unmarshal[MemberKicked](jsonBytes)
+	case bytes.Equal(prefix, memberMutedPrefix):
+		event, err = unmarshal[MemberMuted](jsonBytes)
+	case bytes.Equal(prefix, topicChangedPrefix):
+		event, err = unmarshal[TopicChanged](jsonBytes)
+	case bytes.Equal(prefix, replayTruncatedPrefix):
+		event, err = unmarshal[ReplayTruncated](jsonBytes)
 	default:
 		return nil, fmt.Errorf("unexpected event prefix '%v'", prefix)
 	}
@@ -0,0 +1,36 @@
+package events
+
+import "time"
+
+// Requests to start receiving events of the chat over multiplexed connection.
+// Messages with sequence numbers greater than LastSeq are replayed,
+// or the latest ones if LastSeq is zero.
+type Subscribe struct {
+	Chat    string
+	LastSeq uint64
+}
+
+func (s Subscribe) GetChat() string      { return s.Chat }
+func (s *Subscribe) SetChat(chat string) { s.Chat = chat }
+
+// Requests to stop receiving events of the chat over multiplexed connection.
+type Unsubscribe struct {
+	Chat string
+}
+
+func (u Unsubscribe) GetChat() string      { return u.Chat }
+func (u *Unsubscribe) SetChat(chat string) { u.Chat = chat }
+
+// Notifies client resuming after reconnect that only part of missed messages was
+// replayed. Messages following the one with sequence number Seq should be fetched
+// from chat history.
+type ReplayTruncated struct {
+	Chat string
+	Seq  uint64
+	Time time.Time
+}
+
+func (r ReplayTruncated) GetChat() string         { return r.Chat }
+func (r *ReplayTruncated) SetChat(chat string)    { r.Chat = chat }
+func (r ReplayTruncated) GetTime() time.Time      { return r.Time }
+func (r *ReplayTruncated) SetTime(time time.Time) { r.Time = time }
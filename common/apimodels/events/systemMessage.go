@@ -3,9 +3,12 @@ package events
 import "time"
 
 type SystemMessage struct {
+	Chat string
 	Text string
 	Time time.Time
 }
 
+func (m SystemMessage) GetChat() string         { return m.Chat }
+func (m *SystemMessage) SetChat(chat string)    { m.Chat = chat }
 func (m SystemMessage) GetTime() time.Time      { return m.Time }
 func (m *SystemMessage) SetTime(time time.Time) { m.Time = time }
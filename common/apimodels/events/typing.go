@@ -0,0 +1,19 @@
+package events
+
+import "time"
+
+// Notifies that user is typing a message. Producer is considered typing until
+// Until time, unless a new message from them arrives earlier.
+type Typing struct {
+	Chat     string
+	Producer string
+	Time     time.Time
+	Until    time.Time
+}
+
+func (t Typing) GetChat() string              { return t.Chat }
+func (t *Typing) SetChat(chat string)         { t.Chat = chat }
+func (t Typing) GetProducer() string          { return t.Producer }
+func (t *Typing) SetProducer(producer string) { t.Producer = producer }
+func (t Typing) GetTime() time.Time           { return t.Time }
+func (t *Typing) SetTime(time time.Time)      { t.Time = time }
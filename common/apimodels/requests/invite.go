@@ -0,0 +1,9 @@
+package requests
+
+type Invite struct {
+	// Invite lifetime in seconds.
+	ExpiresIn int `json:"expiresIn" binding:"required,min=60,max=2592000"`
+
+	// Maximum number of times invite can be redeemed, unlimited if zero.
+	MaxUses int `json:"maxUses" binding:"min=0"`
+}
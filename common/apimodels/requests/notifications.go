@@ -0,0 +1,6 @@
+package requests
+
+type MarkNotificationsRead struct {
+	// IDs of notifications to mark as read, all notifications are marked if empty.
+	IDs []string `json:"ids"`
+}
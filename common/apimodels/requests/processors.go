@@ -0,0 +1,6 @@
+package requests
+
+type ChatProcessors struct {
+	// Names of optional event processors to apply to chat, defaults are applied if null.
+	Processors []string `json:"processors"`
+}
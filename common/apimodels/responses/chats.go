@@ -1,5 +1,6 @@
 package responses
 
 type Chats struct {
-	Chats []string `json:"chats"`
+	Chats    []string `json:"chats"`
+	Archived []string `json:"archived"`
 }
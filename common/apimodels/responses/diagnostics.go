@@ -0,0 +1,8 @@
+package responses
+
+type Diagnostics struct {
+	LiveChats          int   `json:"liveChats"`
+	ChatGoroutines     int64 `json:"chatGoroutines"`
+	SlowConsumerEvents int64 `json:"slowConsumerEvents"`
+	Goroutines         int   `json:"goroutines"`
+}
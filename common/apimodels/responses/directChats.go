@@ -0,0 +1,10 @@
+package responses
+
+type DirectChat struct {
+	ChatName string `json:"chatName"`
+	Peer     string `json:"peer"`
+}
+
+type DirectChats struct {
+	Chats []DirectChat `json:"chats"`
+}
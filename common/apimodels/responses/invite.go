@@ -0,0 +1,9 @@
+package responses
+
+import "time"
+
+type Invite struct {
+	Token     string    `json:"token"`
+	ChatName  string    `json:"chatName"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
@@ -0,0 +1,5 @@
+package responses
+
+type Members struct {
+	Members []string `json:"members"`
+}
@@ -0,0 +1,37 @@
+package responses
+
+import "time"
+
+type Message struct {
+	ID       string    `json:"id"`
+	Seq      uint64    `json:"seq"`
+	Producer string    `json:"producer"`
+	Text     string    `json:"text"`
+	Time     time.Time `json:"time"`
+	ReplyTo  string    `json:"replyTo"`
+	Mentions []string  `json:"mentions"`
+	Action   bool      `json:"action"`
+	Edited   bool      `json:"edited"`
+	Deleted  bool      `json:"deleted"`
+
+	Reactions []Reaction `json:"reactions"`
+}
+
+type Reaction struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+}
+
+type Messages struct {
+	Messages []Message `json:"messages"`
+
+	// Indicates whether there are more messages beyond returned page
+	// in the direction of pagination.
+	HasMore bool `json:"hasMore"`
+}
+
+// Message and direct replies to it in chronological order.
+type Thread struct {
+	Parent  Message   `json:"parent"`
+	Replies []Message `json:"replies"`
+}
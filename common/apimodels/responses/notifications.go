@@ -0,0 +1,22 @@
+package responses
+
+import "time"
+
+type Notification struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	ChatName  string    `json:"chatName"`
+	MessageID string    `json:"messageId"`
+	Producer  string    `json:"producer"`
+	Text      string    `json:"text"`
+	Time      time.Time `json:"time"`
+	Read      bool      `json:"read"`
+}
+
+type Notifications struct {
+	// Latest notifications, newest first.
+	Notifications []Notification `json:"notifications"`
+
+	// Number of all unread notifications.
+	Unread int64 `json:"unread"`
+}
@@ -0,0 +1,8 @@
+package responses
+
+type ChatProcessors struct {
+	// Names of optional event processors chat can apply.
+	Available []string `json:"available"`
+	// Names of optional event processors chat applies.
+	Enabled []string `json:"enabled"`
+}
@@ -0,0 +1,11 @@
+package responses
+
+type Role struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+type Roles struct {
+	Owner string `json:"owner"`
+	Roles []Role `json:"roles"`
+}
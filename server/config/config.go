@@ -15,10 +15,35 @@ type Config struct {
 	LogLevel     string
 	PGConnString string
 	Port         int
-	JWT          JWTConfig
-	TLS          TLSConfig
+
+	// Addresses or CIDRs of reverse proxies whose forwarded client IPs are trusted.
+	TrustedProxies []string
+
+	// Usernames of users allowed to view server diagnostics.
+	Admins []string
+
+	// Time given to the server to drain connected clients before exiting.
+	ShutdownTimeout time.Duration
+
+	// Pub/sub used to connect chats across server instances.
+	Backplane Backplane
+
+	JWT       JWTConfig
+	TLS       TLSConfig
+	Chat      ChatConfig
+	Client    ClientConfig
+	RateLimit RateLimitConfig
 }
 
+type Backplane string
+
+const (
+	// Keeps chats within a single server instance.
+	BackplaneMemory Backplane = "memory"
+	// Connects server instances sharing the database using Postgres LISTEN/NOTIFY.
+	BackplanePostgres Backplane = "postgres"
+)
+
 type JWTConfig struct {
 	Key        string
 	Expiration time.Duration
@@ -29,6 +54,67 @@ type TLSConfig struct {
 	KeyPath  string
 }
 
+type ChatConfig struct {
+	// Number of latest messages sent to a client upon joining a chat.
+	HistoryReplaySize int
+
+	// Maximum number of missed messages sent to a client resuming after reconnect.
+	// Client which missed more gets the earliest ones followed by a truncation
+	// notice and has to fetch the rest from chat history.
+	ResumeReplayLimit int
+
+	// Period after which chat without members is unloaded from memory.
+	// Chats are never unloaded if zero.
+	IdleTimeout time.Duration
+
+	// Names of optional event processors applied to chats which weren't configured.
+	DefaultProcessors []string
+
+	// Words masked in messages of chats with word filter enabled.
+	FilteredWords []string
+}
+
+type ClientConfig struct {
+	// Maximum number of outgoing events queued for a single client.
+	QueueSize int
+
+	// What to do when client doesn't consume events fast enough and its queue is full.
+	SlowConsumerPolicy SlowConsumerPolicy
+}
+
+type SlowConsumerPolicy string
+
+const (
+	// Drops the oldest queued event to make room for the new one.
+	DropOldest SlowConsumerPolicy = "drop-oldest"
+	// Drops the new event, keeping queued ones.
+	DropNew SlowConsumerPolicy = "drop-new"
+	// Disconnects the client.
+	Disconnect SlowConsumerPolicy = "disconnect"
+)
+
+type RateLimitConfig struct {
+	// Events a user can send to chats, typing notifications aside.
+	UserEvents Limit
+	// Events all users together can send to a single chat.
+	ChatEvents Limit
+	// REST requests to limited endpoints from a single IP address or user, per endpoint.
+	Requests Limit
+
+	// Number of user event limit violations after which user is muted.
+	MuteAfter int
+	// Period muted user can't send events for.
+	MuteDuration time.Duration
+	// Number of mutes after which user is also disconnected from chat on every next mute.
+	DisconnectAfter int
+}
+
+// Token bucket limit, allows Burst events at once and one more event per Interval.
+type Limit struct {
+	Burst    int
+	Interval time.Duration
+}
+
 func Load(pathes ...string) Config {
 	for _, path := range pathes {
 		godotenv.Load(path)
@@ -41,6 +127,14 @@ func Load(pathes ...string) Config {
 		LogLevel:     getRequiredString(envs, "LOG_LEVEL"),
 		PGConnString: getRequiredString(envs, "PG_CONNECTION_STRING"),
 		Port:         getRequiredInt(envs, "PORT"),
+
+		TrustedProxies: getList(envs, "TRUSTED_PROXIES"),
+		Admins:         getList(envs, "ADMINS"),
+
+		ShutdownTimeout: getRequiredDuration(envs, "SHUTDOWN_TIMEOUT"),
+		Backplane: Backplane(getRequiredOneOf(
+			envs, "BACKPLANE", string(BackplaneMemory), string(BackplanePostgres))),
+
 		JWT: JWTConfig{
 			Key:        getRequiredString(envs, "JWT_KEY"),
 			Expiration: getRequiredDuration(envs, "JWT_EXPIRATION"),
@@ -49,6 +143,36 @@ func Load(pathes ...string) Config {
 			CertPath: getRequiredString(envs, "TLS_CERT_PATH"),
 			KeyPath:  getRequiredString(envs, "TLS_KEY_PATH"),
 		},
+		Chat: ChatConfig{
+			HistoryReplaySize: getRequiredInt(envs, "CHAT_HISTORY_REPLAY_SIZE"),
+			ResumeReplayLimit: getRequiredInt(envs, "CHAT_RESUME_REPLAY_LIMIT"),
+			IdleTimeout:       getRequiredDuration(envs, "CHAT_IDLE_TIMEOUT"),
+			DefaultProcessors: getList(envs, "CHAT_DEFAULT_PROCESSORS"),
+			FilteredWords:     getList(envs, "CHAT_FILTERED_WORDS"),
+		},
+		Client: ClientConfig{
+			QueueSize: getRequiredInt(envs, "CLIENT_QUEUE_SIZE"),
+			SlowConsumerPolicy: SlowConsumerPolicy(getRequiredOneOf(
+				envs, "CLIENT_SLOW_CONSUMER_POLICY",
+				string(DropOldest), string(DropNew), string(Disconnect))),
+		},
+		RateLimit: RateLimitConfig{
+			UserEvents: Limit{
+				Burst:    getRequiredInt(envs, "RATE_LIMIT_USER_BURST"),
+				Interval: getRequiredDuration(envs, "RATE_LIMIT_USER_INTERVAL"),
+			},
+			ChatEvents: Limit{
+				Burst:    getRequiredInt(envs, "RATE_LIMIT_CHAT_BURST"),
+				Interval: getRequiredDuration(envs, "RATE_LIMIT_CHAT_INTERVAL"),
+			},
+			Requests: Limit{
+				Burst:    getRequiredInt(envs, "RATE_LIMIT_REQUESTS_BURST"),
+				Interval: getRequiredDuration(envs, "RATE_LIMIT_REQUESTS_INTERVAL"),
+			},
+			MuteAfter:       getRequiredInt(envs, "RATE_LIMIT_MUTE_AFTER"),
+			MuteDuration:    getRequiredDuration(envs, "RATE_LIMIT_MUTE_DURATION"),
+			DisconnectAfter: getRequiredInt(envs, "RATE_LIMIT_DISCONNECT_AFTER"),
+		},
 	}
 }
 
@@ -63,6 +187,18 @@ func loadEnvsMap() map[string]string {
 	return envsMap
 }
 
+// Gets comma separated values, empty list if config is empty or missing.
+func getList(envs map[string]string, key string) []string {
+	values := []string{}
+	for _, value := range strings.Split(envs[key], ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
 func getRequiredDuration(envs map[string]string, key string) time.Duration {
 	s := getRequiredString(envs, key)
 	d, err := time.ParseDuration(s)
@@ -83,6 +219,18 @@ func getRequiredInt(envs map[string]string, key string) int {
 	return i
 }
 
+func getRequiredOneOf(envs map[string]string, key string, values ...string) string {
+	s := getRequiredString(envs, key)
+	for _, value := range values {
+		if s == value {
+			return s
+		}
+	}
+
+	log.Fatalf(`"%s" config value '%s' is not one of %v`, key, s, values)
+	return ""
+}
+
 func getRequiredString(envs map[string]string, key string) string {
 	value := envs[key]
 	if value == "" {
@@ -1,36 +1,79 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shkotk/gochat/common/apimodels/requests"
 	"github.com/shkotk/gochat/common/apimodels/responses"
 	"github.com/shkotk/gochat/server/interfaces"
 	"github.com/shkotk/gochat/server/middleware"
+	"github.com/shkotk/gochat/server/models"
+	"github.com/shkotk/gochat/server/repositories"
 	"github.com/shkotk/gochat/server/services"
 	"github.com/shkotk/gochat/server/websocket"
 	"github.com/sirupsen/logrus"
 )
 
 type ChatController struct {
-	logger      *logrus.Logger
-	jwtManager  *services.JWTManager
-	chatManager interfaces.ChatManager
+	logger               *logrus.Logger
+	jwtManager           *services.JWTManager
+	chatManager          interfaces.ChatManager
+	clientRegistry       *services.ClientRegistry
+	clientFactory        *websocket.ClientFactory
+	userRepository       *repositories.UserRepository
+	chatRepository       *repositories.ChatRepository
+	chatMemberRepository *repositories.ChatMemberRepository
+	chatInviteRepository *repositories.ChatInviteRepository
+	messageRepository    *repositories.MessageRepository
+	reactionRepository   *repositories.ReactionRepository
+	processorChain       *services.EventProcessorChain
 }
 
 func NewChatController(
 	logger *logrus.Logger,
 	jwtManager *services.JWTManager,
 	chatManager interfaces.ChatManager,
+	clientRegistry *services.ClientRegistry,
+	clientFactory *websocket.ClientFactory,
+	userRepository *repositories.UserRepository,
+	chatRepository *repositories.ChatRepository,
+	chatMemberRepository *repositories.ChatMemberRepository,
+	chatInviteRepository *repositories.ChatInviteRepository,
+	messageRepository *repositories.MessageRepository,
+	reactionRepository *repositories.ReactionRepository,
+	processorChain *services.EventProcessorChain,
 ) *ChatController {
-	return &ChatController{logger, jwtManager, chatManager}
+	return &ChatController{
+		logger,
+		jwtManager,
+		chatManager,
+		clientRegistry,
+		clientFactory,
+		userRepository,
+		chatRepository,
+		chatMemberRepository,
+		chatInviteRepository,
+		messageRepository,
+		reactionRepository,
+		processorChain,
+	}
 }
 
 type createRequest struct {
 	ChatName string `uri:"chatName" binding:"required,name"`
 }
 
+type createQuery struct {
+	Private bool `form:"private"`
+}
+
 func (c *ChatController) Create(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
 	var request createRequest
 	if err := ctx.ShouldBindUri(&request); err != nil {
 		ctx.Error(err)
@@ -38,7 +81,15 @@ func (c *ChatController) Create(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.chatManager.Create(request.ChatName); err != nil {
+	var query createQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	err := c.chatManager.Create(ctx, request.ChatName, claims.Username, query.Private)
+	if err != nil {
 		ctx.Error(err)
 		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()}) // TODO may be 500
 		return
@@ -48,14 +99,87 @@ func (c *ChatController) Create(ctx *gin.Context) {
 }
 
 func (c *ChatController) List(ctx *gin.Context) {
-	chats, err := c.chatManager.List()
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	chats, err := c.chatRepository.GetVisible(ctx, claims.Username)
 	if err != nil {
 		ctx.Error(err)
 		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, responses.Chats{Chats: chats})
+	response := responses.Chats{Chats: []string{}, Archived: []string{}}
+	for _, chat := range chats {
+		if chat.Archived {
+			response.Archived = append(response.Archived, chat.Name)
+		} else {
+			response.Chats = append(response.Chats, chat.Name)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+type deleteRequest struct {
+	ChatName string `uri:"chatName" binding:"required,name"`
+}
+
+func (c *ChatController) Delete(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request deleteRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	if _, ok := c.authorizeOwner(ctx, claims.Username, request.ChatName); !ok {
+		return
+	}
+
+	if err := c.chatManager.Delete(ctx, request.ChatName); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+type archiveRequest struct {
+	ChatName string `uri:"chatName" binding:"required,name"`
+}
+
+func (c *ChatController) Archive(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request archiveRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	chat, ok := c.authorizeOwner(ctx, claims.Username, request.ChatName)
+	if !ok {
+		return
+	}
+
+	if chat.Archived {
+		ctx.JSON(http.StatusBadRequest, responses.Error{
+			Error: fmt.Sprintf("Chat '%v' is already archived", request.ChatName),
+		})
+		return
+	}
+
+	if err := c.chatManager.Archive(ctx, request.ChatName); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
 }
 
 type joinRequest struct {
@@ -72,6 +196,23 @@ func (c *ChatController) Join(ctx *gin.Context) {
 		return
 	}
 
+	c.join(ctx, claims.Username, request.ChatName)
+}
+
+// Sequence number of the last message client received before reconnecting.
+type joinQuery struct {
+	LastSeq uint64 `form:"lastSeq"`
+}
+
+// Upgrades connection to WebSocket and adds user's client to the chat.
+func (c *ChatController) join(ctx *gin.Context, username, chatName string) {
+	var query joinQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
 	conn, err := websocket.Upgrade(ctx.Writer, ctx.Request)
 	if err != nil {
 		c.logger.WithError(err).Warn("Failed to upgrade connection.")
@@ -82,21 +223,654 @@ func (c *ChatController) Join(ctx *gin.Context) {
 		return
 	}
 
-	client := websocket.NewClient(claims.Username, conn, c.logger)
-	err = c.chatManager.AddClient(client, request.ChatName)
+	client := c.clientFactory.NewClient(username, conn)
+	err = c.chatManager.AddClient(ctx, client, chatName, query.LastSeq)
 	if err != nil {
 		c.logger.WithError(err).Warnf(
-			"Failed to add user '%s' to chat '%s'.", claims.Username, request.ChatName)
+			"Failed to add user '%s' to chat '%s'.", username, chatName)
 		ctx.Error(err)
 
 		if err = conn.Close(); err != nil {
 			c.logger.WithError(err).Warnf(
-				"Failed to close connection with user '%s'.", request.ChatName)
+				"Failed to close connection with user '%s'.", username)
 			ctx.Error(err)
 		}
 
 		return
 	}
 
+	c.clientRegistry.Register(client)
 	go client.Run()
 }
+
+// Upgrades connection to WebSocket, which client can use to subscribe to several chats.
+func (c *ChatController) Connect(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	conn, err := websocket.Upgrade(ctx.Writer, ctx.Request)
+	if err != nil {
+		c.logger.WithError(err).Warn("Failed to upgrade connection.")
+		ctx.Error(err)
+		if !ctx.Writer.Written() {
+			ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		}
+		return
+	}
+
+	client := c.clientFactory.NewClient(claims.Username, conn)
+	session := services.NewSession(client, c.chatManager, c.logger)
+
+	c.clientRegistry.Register(client)
+	go session.Run()
+	go client.Run()
+}
+
+const defaultHistoryPageSize = 50
+
+type historyRequest struct {
+	ChatName string `uri:"chatName" binding:"required,name"`
+}
+
+// Message sequence numbers and times to paginate history by.
+type historyQuery struct {
+	Before     uint64    `form:"before"`
+	After      uint64    `form:"after"`
+	BeforeTime time.Time `form:"beforeTime"`
+	AfterTime  time.Time `form:"afterTime"`
+	Limit      int       `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+func (c *ChatController) History(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request historyRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	c.history(ctx, claims.Username, request.ChatName)
+}
+
+// Writes page of chat messages requested by query parameters if user can see the chat.
+func (c *ChatController) history(ctx *gin.Context, username, chatName string) {
+	var query historyQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultHistoryPageSize
+	}
+
+	if _, ok := c.authorizeMember(ctx, username, chatName); !ok {
+		return
+	}
+
+	messages, hasMore, err := c.messageRepository.GetPage(
+		ctx, chatName, repositories.MessagePage{
+			BeforeSeq:  query.Before,
+			AfterSeq:   query.After,
+			BeforeTime: query.BeforeTime,
+			AfterTime:  query.AfterTime,
+			Limit:      query.Limit,
+		})
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	messagesResponse, ok := c.toMessageResponses(ctx, messages)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, responses.Messages{Messages: messagesResponse, HasMore: hasMore})
+}
+
+type threadRequest struct {
+	ChatName  string `uri:"chatName" binding:"required,name"`
+	MessageID string `uri:"messageID" binding:"required,uuid"`
+}
+
+// Writes message and replies to it if user can see the chat.
+func (c *ChatController) Thread(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request threadRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	c.thread(ctx, claims.Username, request.ChatName, request.MessageID)
+}
+
+// Writes message with replies to it from the chat, shared by group and direct chats.
+func (c *ChatController) thread(ctx *gin.Context, username, chatName, messageID string) {
+	if _, ok := c.authorizeMember(ctx, username, chatName); !ok {
+		return
+	}
+
+	parent, err := c.messageRepository.Get(ctx, chatName, messageID)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+	if parent == nil {
+		ctx.JSON(http.StatusNotFound, responses.Error{
+			Error: fmt.Sprintf("Message '%v' does not exist", messageID),
+		})
+		return
+	}
+
+	replies, err := c.messageRepository.GetReplies(ctx, chatName, messageID)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	messagesResponse, ok := c.toMessageResponses(ctx, append([]models.Message{*parent}, replies...))
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, responses.Thread{
+		Parent:  messagesResponse[0],
+		Replies: messagesResponse[1:],
+	})
+}
+
+// Converts messages to responses along with their reactions.
+// Writes error response and returns false if reactions failed to load.
+func (c *ChatController) toMessageResponses(
+	ctx *gin.Context, messages []models.Message,
+) ([]responses.Message, bool) {
+	messageIDs := make([]string, len(messages))
+	for i, message := range messages {
+		messageIDs[i] = message.ID
+	}
+	reactions, err := c.reactionRepository.GetCounts(ctx, messageIDs)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return nil, false
+	}
+
+	result := make([]responses.Message, len(messages))
+	for i, message := range messages {
+		result[i] = responses.Message{
+			ID:       message.ID,
+			Seq:      message.Seq,
+			Producer: message.Producer,
+			Text:     message.Text,
+			Time:     message.Time,
+			ReplyTo:  message.ReplyTo,
+			Mentions: message.Mentions,
+			Action:   message.Action,
+			Edited:   message.Edited,
+			Deleted:  message.Deleted,
+
+			Reactions: make([]responses.Reaction, len(reactions[message.ID])),
+		}
+		for j, count := range reactions[message.ID] {
+			result[i].Reactions[j] = responses.Reaction{
+				Emoji: count.Emoji,
+				Count: count.Count,
+			}
+		}
+	}
+
+	return result, true
+}
+
+type rolesRequest struct {
+	ChatName string `uri:"chatName" binding:"required,name"`
+}
+
+func (c *ChatController) Roles(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request rolesRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	chat, ok := c.authorizeMember(ctx, claims.Username, request.ChatName)
+	if !ok {
+		return
+	}
+
+	members, err := c.chatMemberRepository.GetAll(ctx, request.ChatName)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	response := responses.Roles{
+		Owner: chat.Owner,
+		Roles: make([]responses.Role, len(members)),
+	}
+	for i, member := range members {
+		response.Roles[i] = responses.Role{
+			Username: member.Username,
+			Role:     string(member.Role),
+		}
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+type membersRequest struct {
+	ChatName string `uri:"chatName" binding:"required,name"`
+}
+
+// Gets users currently in the chat.
+func (c *ChatController) Members(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request membersRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	c.members(ctx, claims.Username, request.ChatName)
+}
+
+// Writes users currently in chat if user can see the chat.
+func (c *ChatController) members(ctx *gin.Context, username, chatName string) {
+	chat, ok := c.authorizeMember(ctx, username, chatName)
+	if !ok {
+		return
+	}
+	if chat.Archived {
+		ctx.JSON(http.StatusOK, responses.Members{Members: []string{}})
+		return
+	}
+
+	members, err := c.chatManager.Members(ctx, chatName)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, responses.Members{Members: members})
+}
+
+type grantRoleRequest struct {
+	ChatName string `uri:"chatName" binding:"required,name"`
+	Username string `uri:"username" binding:"required,name"`
+	Role     string `uri:"role" binding:"required,oneof=moderator member"`
+}
+
+func (c *ChatController) GrantRole(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request grantRoleRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	if !c.authorizeRoleChange(ctx, claims.Username, request.ChatName, request.Username) {
+		return
+	}
+
+	exists, err := c.userRepository.Exists(ctx, request.Username)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+	if !exists {
+		ctx.JSON(http.StatusNotFound, responses.Error{
+			Error: fmt.Sprintf("User '%v' does not exist", request.Username),
+		})
+		return
+	}
+
+	err = c.chatMemberRepository.Save(ctx, models.ChatMember{
+		ChatName: request.ChatName,
+		Username: request.Username,
+		Role:     models.Role(request.Role),
+	})
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+type revokeRoleRequest struct {
+	ChatName string `uri:"chatName" binding:"required,name"`
+	Username string `uri:"username" binding:"required,name"`
+}
+
+// Demotes moderator to regular member, who stays a member of private chat.
+func (c *ChatController) RevokeRole(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request revokeRoleRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	if !c.authorizeRoleChange(ctx, claims.Username, request.ChatName, request.Username) {
+		return
+	}
+
+	role, ok := c.getRole(ctx, request.ChatName, request.Username)
+	if !ok {
+		return
+	}
+	if role != models.RoleModerator {
+		ctx.Status(http.StatusOK)
+		return
+	}
+
+	err := c.chatMemberRepository.Save(ctx, models.ChatMember{
+		ChatName: request.ChatName,
+		Username: request.Username,
+		Role:     models.RoleMember,
+	})
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+type processorsRequest struct {
+	ChatName string `uri:"chatName" binding:"required,name"`
+}
+
+// Gets optional event processors chat can apply and those it applies.
+func (c *ChatController) Processors(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request processorsRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	chat, ok := c.authorizeModerator(ctx, claims.Username, request.ChatName)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, responses.ChatProcessors{
+		Available: c.processorChain.Optional(),
+		Enabled:   c.processorChain.Enabled(*chat),
+	})
+}
+
+type configureProcessorsRequest struct {
+	ChatName string `uri:"chatName" binding:"required,name"`
+}
+
+// Sets optional event processors chat applies.
+func (c *ChatController) ConfigureProcessors(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request configureProcessorsRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	var body requests.ChatProcessors
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	for _, name := range body.Processors {
+		if !c.processorChain.IsOptional(name) {
+			ctx.JSON(http.StatusBadRequest, responses.Error{
+				Error: fmt.Sprintf("'%v' is not an optional event processor", name),
+			})
+			return
+		}
+	}
+
+	if _, ok := c.authorizeOwner(ctx, claims.Username, request.ChatName); !ok {
+		return
+	}
+
+	err := c.processorChain.Configure(ctx, request.ChatName, body.Processors)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+type inviteRequest struct {
+	ChatName string `uri:"chatName" binding:"required,name"`
+}
+
+func (c *ChatController) Invite(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request inviteRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	var body requests.Invite
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	if _, ok := c.authorizeModerator(ctx, claims.Username, request.ChatName); !ok {
+		return
+	}
+
+	invite := models.ChatInvite{
+		Token:     uuid.NewString(),
+		ChatName:  request.ChatName,
+		CreatedBy: claims.Username,
+		ExpiresAt: time.Now().Add(time.Duration(body.ExpiresIn) * time.Second),
+		MaxUses:   body.MaxUses,
+	}
+	if err := c.chatInviteRepository.Create(ctx, invite); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, responses.Invite{
+		Token:     invite.Token,
+		ChatName:  invite.ChatName,
+		ExpiresAt: invite.ExpiresAt,
+	})
+}
+
+type redeemInviteRequest struct {
+	Token string `uri:"token" binding:"required,uuid"`
+}
+
+func (c *ChatController) RedeemInvite(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request redeemInviteRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	invite, err := c.chatInviteRepository.Redeem(ctx, request.Token, claims.Username)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	if invite == nil {
+		ctx.JSON(http.StatusNotFound, responses.Error{Error: "Invite is invalid or expired"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, responses.Invite{
+		Token:     invite.Token,
+		ChatName:  invite.ChatName,
+		ExpiresAt: invite.ExpiresAt,
+	})
+}
+
+// Checks that user is allowed to change role of target user in the chat.
+// Writes error response and returns false otherwise.
+func (c *ChatController) authorizeRoleChange(
+	ctx *gin.Context, username, chatName, targetUsername string,
+) bool {
+	chat, ok := c.authorizeOwner(ctx, username, chatName)
+	if !ok {
+		return false
+	}
+
+	if targetUsername == chat.Owner {
+		ctx.JSON(http.StatusBadRequest, responses.Error{
+			Error: "Role of chat owner can't be changed",
+		})
+		return false
+	}
+
+	return true
+}
+
+// Gets chat with provided name and checks that user can see it, i.e. chat is
+// public or user is its member. Writes error response and returns false otherwise.
+func (c *ChatController) authorizeMember(
+	ctx *gin.Context, username, chatName string,
+) (*models.Chat, bool) {
+	chat, ok := c.getChat(ctx, chatName)
+	if !ok || !chat.Private {
+		return chat, ok
+	}
+
+	role, ok := c.getRole(ctx, chatName, username)
+	if !ok {
+		return nil, false
+	}
+
+	if role == "" {
+		// private chats are indistinguishable from missing ones for non-members
+		ctx.JSON(http.StatusNotFound, responses.Error{
+			Error: fmt.Sprintf("Chat '%v' does not exist", chatName),
+		})
+		return nil, false
+	}
+
+	return chat, true
+}
+
+// Gets chat with provided name and checks that user is its owner or moderator.
+// Writes error response and returns false otherwise.
+func (c *ChatController) authorizeModerator(
+	ctx *gin.Context, username, chatName string,
+) (*models.Chat, bool) {
+	chat, ok := c.authorizeMember(ctx, username, chatName)
+	if !ok {
+		return nil, false
+	}
+
+	role, ok := c.getRole(ctx, chatName, username)
+	if !ok {
+		return nil, false
+	}
+
+	if !role.CanModerate() {
+		ctx.JSON(http.StatusForbidden, responses.Error{
+			Error: fmt.Sprintf("Only owner or moderators of chat '%v' can do that", chatName),
+		})
+		return nil, false
+	}
+
+	return chat, true
+}
+
+// Gets chat with provided name and checks that user is its owner. Like authorizeMember,
+// reports private chat as missing to non-members. Writes error response and returns
+// false otherwise.
+func (c *ChatController) authorizeOwner(
+	ctx *gin.Context, username, chatName string,
+) (*models.Chat, bool) {
+	chat, ok := c.authorizeMember(ctx, username, chatName)
+	if !ok {
+		return nil, false
+	}
+
+	if chat.Owner != username {
+		ctx.JSON(http.StatusForbidden, responses.Error{
+			Error: fmt.Sprintf("Only owner of chat '%v' can do that", chatName),
+		})
+		return nil, false
+	}
+
+	return chat, true
+}
+
+// Gets role of the user in the chat. Writes error response and returns false
+// if role can't be retrieved.
+func (c *ChatController) getRole(
+	ctx *gin.Context, chatName, username string,
+) (models.Role, bool) {
+	role, err := c.chatMemberRepository.GetRole(ctx, chatName, username)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return "", false
+	}
+
+	return role, true
+}
+
+// Gets chat with provided name. Writes error response and returns false
+// if chat can't be retrieved or doesn't exist.
+func (c *ChatController) getChat(ctx *gin.Context, chatName string) (*models.Chat, bool) {
+	chat, err := c.chatRepository.Get(ctx, chatName)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return nil, false
+	}
+
+	if chat == nil {
+		ctx.JSON(http.StatusNotFound, responses.Error{
+			Error: fmt.Sprintf("Chat '%v' does not exist", chatName),
+		})
+		return nil, false
+	}
+
+	return chat, true
+}
@@ -1,3 +1,60 @@
 package controllers
 
-// TODO
+import (
+	"context"
+	"net/http"
+
+	"github.com/shkotk/gochat/server/config"
+	"github.com/shkotk/gochat/server/models"
+	"github.com/shkotk/gochat/server/repositories"
+	"github.com/shkotk/gochat/server/services"
+	"github.com/sirupsen/logrus"
+)
+
+// Client which drops events sent to it.
+type fakeClient struct {
+	username string
+	done     chan struct{}
+}
+
+func newFakeClient(username string) *fakeClient {
+	return &fakeClient{username, make(chan struct{})}
+}
+
+func (c *fakeClient) ID() string            { return c.username }
+func (c *fakeClient) In() <-chan any        { return nil }
+func (c *fakeClient) Send(any)              {}
+func (c *fakeClient) Done() <-chan struct{} { return c.done }
+func (c *fakeClient) Close(int, string)     {}
+
+func (s *DBTestSuite) TestChat_RevokeRole_ModeratorOfPrivateChat_CanStillJoin() {
+	s.testDB.Create(&models.Chat{
+		Name:    "secret",
+		Owner:   "michael",
+		Private: true,
+		Members: []models.ChatMember{
+			{Username: "michael", Role: models.RoleOwner},
+			{Username: "dwight", Role: models.RoleModerator},
+		},
+	})
+	logger := logrus.StandardLogger()
+	chatManager := services.NewChatManager(config.Config{}, logger,
+		services.NewMemoryBackplane(logger), nil, nil,
+		repositories.NewChatRepository(logger, s.testDB),
+		repositories.NewChatMemberRepository(logger, s.testDB),
+		repositories.NewMessageRepository(logger, s.testDB),
+		repositories.NewReactionRepository(logger, s.testDB),
+		repositories.NewNotificationRepository(logger, s.testDB))
+	defer chatManager.Shutdown(context.Background())
+	controller := s.newChatController(chatManager)
+
+	recorder := serve(controller.RevokeRole, http.MethodDelete,
+		"/chat/roles/:chatName/:username", "/chat/roles/secret/dwight", "michael")
+
+	s.Equal(http.StatusOK, recorder.Code)
+	role, err := repositories.NewChatMemberRepository(logger, s.testDB).
+		GetRole(context.Background(), "secret", "dwight")
+	s.Nil(err)
+	s.Equal(models.RoleMember, role)
+	s.Nil(chatManager.AddClient(context.Background(), newFakeClient("dwight"), "secret", 0))
+}
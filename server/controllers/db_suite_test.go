@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/shkotk/gochat/common/validation"
+	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/shkotk/gochat/server/middleware"
+	"github.com/shkotk/gochat/server/repositories"
+	"github.com/shkotk/gochat/server/services"
+	"github.com/shkotk/gochat/server/test"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+// This test suite is responsible for setting up and tearing down DB for tests.
+type DBTestSuite struct {
+	suite.Suite
+	testDB *gorm.DB
+	dropDB func()
+}
+
+func (s *DBTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterValidation("name", validation.IsValidName)
+	}
+
+	s.testDB, s.dropDB = test.CreateDB(test.LoadConfig("../.test.env"))
+}
+
+func (s *DBTestSuite) TearDownTest() {
+	test.TruncateDB(s.testDB)
+}
+
+func (s *DBTestSuite) TearDownSuite() {
+	s.dropDB()
+}
+
+func TestDBSuite(t *testing.T) {
+	suite.Run(t, new(DBTestSuite))
+}
+
+// Creates controller backed by test DB. Only endpoints which don't connect
+// clients can be served by it.
+func (s *DBTestSuite) newChatController(chatManager interfaces.ChatManager) *ChatController {
+	logger := logrus.StandardLogger()
+	return NewChatController(
+		logger, nil, chatManager, nil, nil,
+		repositories.NewUserRepository(logger, s.testDB),
+		repositories.NewChatRepository(logger, s.testDB),
+		repositories.NewChatMemberRepository(logger, s.testDB),
+		repositories.NewChatInviteRepository(logger, s.testDB),
+		repositories.NewMessageRepository(logger, s.testDB),
+		repositories.NewReactionRepository(logger, s.testDB),
+		nil)
+}
+
+// Serves request to handler registered with route on behalf of the user.
+func serve(
+	handler gin.HandlerFunc, method, route, target, username string,
+) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Handle(method, route, func(ctx *gin.Context) {
+		ctx.Set(middleware.UserClaimsKey, services.UserClaims{Username: username})
+	}, handler)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+
+	return recorder
+}
+
+// Chat manager reporting fixed members of any chat.
+type fakeChatManager struct {
+	interfaces.ChatManager
+	members []string
+}
+
+func (m fakeChatManager) Members(context.Context, string) ([]string, error) {
+	return m.members, nil
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shkotk/gochat/common/apimodels/responses"
+	"github.com/shkotk/gochat/server/config"
+	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/shkotk/gochat/server/middleware"
+	"github.com/shkotk/gochat/server/services"
+	"github.com/shkotk/gochat/server/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+type DiagnosticsController struct {
+	logger        *logrus.Logger
+	admins        map[string]struct{}
+	chatManager   interfaces.ChatManager
+	clientFactory *websocket.ClientFactory
+}
+
+func NewDiagnosticsController(
+	cfg config.Config,
+	logger *logrus.Logger,
+	chatManager interfaces.ChatManager,
+	clientFactory *websocket.ClientFactory,
+) *DiagnosticsController {
+	admins := make(map[string]struct{}, len(cfg.Admins))
+	for _, username := range cfg.Admins {
+		admins[username] = struct{}{}
+	}
+
+	return &DiagnosticsController{logger, admins, chatManager, clientFactory}
+}
+
+// Gets server diagnostics, available to configured admins only.
+func (c *DiagnosticsController) Get(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+	if _, ok := c.admins[claims.Username]; !ok {
+		ctx.JSON(http.StatusForbidden, responses.Error{
+			Error: "Only admins can view diagnostics",
+		})
+		return
+	}
+
+	stats := c.chatManager.Stats()
+
+	ctx.JSON(http.StatusOK, responses.Diagnostics{
+		LiveChats:          stats.LiveChats,
+		ChatGoroutines:     stats.ChatGoroutines,
+		SlowConsumerEvents: c.clientFactory.SlowConsumerEvents(),
+		Goroutines:         runtime.NumGoroutine(),
+	})
+}
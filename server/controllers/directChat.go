@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shkotk/gochat/common/apimodels/responses"
+	"github.com/shkotk/gochat/server/middleware"
+	"github.com/shkotk/gochat/server/services"
+)
+
+type directChatRequest struct {
+	Username string `uri:"username" binding:"required,name"`
+}
+
+func (c *ChatController) OpenDirect(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request directChatRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	if request.Username == claims.Username {
+		ctx.JSON(http.StatusBadRequest, responses.Error{
+			Error: "Can't open direct chat with yourself",
+		})
+		return
+	}
+
+	exists, err := c.userRepository.Exists(ctx, request.Username)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+	if !exists {
+		ctx.JSON(http.StatusNotFound, responses.Error{
+			Error: fmt.Sprintf("User '%v' does not exist", request.Username),
+		})
+		return
+	}
+
+	chatName, err := c.chatManager.OpenDirect(ctx, claims.Username, request.Username)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, responses.DirectChat{
+		ChatName: chatName,
+		Peer:     request.Username,
+	})
+}
+
+func (c *ChatController) ListDirect(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	chats, err := c.chatRepository.GetDirect(ctx, claims.Username)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	response := responses.DirectChats{Chats: make([]responses.DirectChat, len(chats))}
+	for i, chat := range chats {
+		response.Chats[i] = responses.DirectChat{
+			ChatName: chat.Name,
+			Peer:     services.DirectChatPeer(chat.Name, claims.Username),
+		}
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (c *ChatController) JoinDirect(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request directChatRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	c.join(ctx, claims.Username, services.DirectChatName(claims.Username, request.Username))
+}
+
+func (c *ChatController) DirectHistory(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request directChatRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	c.history(ctx, claims.Username, services.DirectChatName(claims.Username, request.Username))
+}
+
+func (c *ChatController) DirectMembers(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request directChatRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	c.members(ctx, claims.Username, services.DirectChatName(claims.Username, request.Username))
+}
+
+type directThreadRequest struct {
+	Username  string `uri:"username" binding:"required,name"`
+	MessageID string `uri:"messageID" binding:"required,uuid"`
+}
+
+func (c *ChatController) DirectThread(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var request directThreadRequest
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	c.thread(ctx, claims.Username,
+		services.DirectChatName(claims.Username, request.Username), request.MessageID)
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shkotk/gochat/common/apimodels/responses"
+	"github.com/shkotk/gochat/server/models"
+	"github.com/shkotk/gochat/server/services"
+)
+
+// Creates direct chat between jim and pam with a message and reply to it.
+// Returns ID of the message.
+func (s *DBTestSuite) createTestDirectChat() string {
+	chatName := services.DirectChatName("jim", "pam")
+	s.testDB.Create([]models.User{
+		{Username: "jim", PasswordHash: "-"},
+		{Username: "pam", PasswordHash: "-"},
+	})
+	s.testDB.Create(&models.Chat{
+		Name:    chatName,
+		Private: true,
+		Direct:  true,
+		Members: []models.ChatMember{
+			{Username: "jim", Role: models.RoleMember},
+			{Username: "pam", Role: models.RoleMember},
+		},
+	})
+	messageID := uuid.NewString()
+	s.testDB.Create([]models.Message{
+		{ID: messageID, ChatName: chatName, Seq: 1, Producer: "jim", Text: "hi", Time: time.Now()},
+		{ID: uuid.NewString(), ChatName: chatName, Seq: 2, Producer: "pam", Text: "hey",
+			Time: time.Now(), ReplyTo: messageID},
+	})
+
+	return messageID
+}
+
+func (s *DBTestSuite) TestChat_DirectHistory_Participant_ReturnsMessages() {
+	s.createTestDirectChat()
+	controller := s.newChatController(fakeChatManager{})
+
+	recorder := serve(controller.DirectHistory,
+		http.MethodGet, "/dm/history/:username", "/dm/history/pam", "jim")
+
+	s.Equal(http.StatusOK, recorder.Code)
+	var response responses.Messages
+	s.Nil(json.Unmarshal(recorder.Body.Bytes(), &response))
+	s.Len(response.Messages, 2)
+}
+
+func (s *DBTestSuite) TestChat_DirectMembers_Participant_ReturnsMembers() {
+	s.createTestDirectChat()
+	controller := s.newChatController(fakeChatManager{members: []string{"jim", "pam"}})
+
+	recorder := serve(controller.DirectMembers,
+		http.MethodGet, "/dm/members/:username", "/dm/members/jim", "pam")
+
+	s.Equal(http.StatusOK, recorder.Code)
+	var response responses.Members
+	s.Nil(json.Unmarshal(recorder.Body.Bytes(), &response))
+	s.Equal([]string{"jim", "pam"}, response.Members)
+}
+
+func (s *DBTestSuite) TestChat_DirectThread_Participant_ReturnsReplies() {
+	messageID := s.createTestDirectChat()
+	controller := s.newChatController(fakeChatManager{})
+
+	recorder := serve(controller.DirectThread,
+		http.MethodGet, "/dm/thread/:username/:messageID", "/dm/thread/pam/"+messageID, "jim")
+
+	s.Equal(http.StatusOK, recorder.Code)
+	var response responses.Thread
+	s.Nil(json.Unmarshal(recorder.Body.Bytes(), &response))
+	s.Equal(messageID, response.Parent.ID)
+	s.Len(response.Replies, 1)
+}
+
+func (s *DBTestSuite) TestChat_DirectMembers_NotParticipant_ReturnsNotFound() {
+	s.createTestDirectChat()
+	s.testDB.Create(&models.User{Username: "dwight", PasswordHash: "-"})
+	controller := s.newChatController(fakeChatManager{})
+
+	recorder := serve(controller.DirectMembers,
+		http.MethodGet, "/dm/members/:username", "/dm/members/jim", "dwight")
+
+	s.Equal(http.StatusNotFound, recorder.Code)
+}
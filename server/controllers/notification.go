@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shkotk/gochat/common/apimodels/requests"
+	"github.com/shkotk/gochat/common/apimodels/responses"
+	"github.com/shkotk/gochat/server/middleware"
+	"github.com/shkotk/gochat/server/repositories"
+	"github.com/shkotk/gochat/server/services"
+	"github.com/sirupsen/logrus"
+)
+
+// Maximal number of notifications returned by List.
+const notificationsListLimit = 100
+
+type NotificationController struct {
+	logger                 *logrus.Logger
+	notificationRepository *repositories.NotificationRepository
+}
+
+func NewNotificationController(
+	logger *logrus.Logger,
+	notificationRepository *repositories.NotificationRepository,
+) *NotificationController {
+	return &NotificationController{logger, notificationRepository}
+}
+
+// Writes the latest notifications of the user and number of unread ones.
+func (c *NotificationController) List(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	notifications, err := c.notificationRepository.GetLast(
+		ctx, claims.Username, notificationsListLimit)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	unread, err := c.notificationRepository.CountUnread(ctx, claims.Username)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	response := responses.Notifications{
+		Notifications: make([]responses.Notification, len(notifications)),
+		Unread:        unread,
+	}
+	for i, notification := range notifications {
+		response.Notifications[i] = responses.Notification{
+			ID:        notification.ID,
+			Kind:      string(notification.Kind),
+			ChatName:  notification.ChatName,
+			MessageID: notification.MessageID,
+			Producer:  notification.Producer,
+			Text:      notification.Text,
+			Time:      notification.Time,
+			Read:      notification.Read,
+		}
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (c *NotificationController) MarkRead(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	var body requests.MarkNotificationsRead
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	err := c.notificationRepository.MarkRead(ctx, claims.Username, body.IDs)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (c *NotificationController) Clear(ctx *gin.Context) {
+	claims := ctx.MustGet(middleware.UserClaimsKey).(services.UserClaims)
+
+	err := c.notificationRepository.Clear(ctx, claims.Username)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
@@ -0,0 +1,14 @@
+package interfaces
+
+import "context"
+
+// Pub/sub channel connecting server instances. Messages are delivered to all
+// subscribers of the topic, including ones of the publishing instance.
+type Backplane interface {
+	// Publishes payload to all subscribers of the topic.
+	Publish(ctx context.Context, topic string, payload []byte) error
+
+	// Subscribes to payloads published to the topic until context is done.
+	// Returned channel is closed once subscription ends.
+	Subscribe(ctx context.Context, topic string) <-chan []byte
+}
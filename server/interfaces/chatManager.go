@@ -1,12 +1,40 @@
 package interfaces
 
+import "context"
+
 type ChatManager interface {
-	// Creates new Chat with specified chat name.
-	Create(chatName string) error
+	// Creates new Chat with specified chat name and owner and persists it.
+	// Private chats can be joined by their members only.
+	Create(ctx context.Context, chatName, owner string, private bool) error
+
+	// Creates direct chat between two users unless it already exists.
+	// Returns direct chat name.
+	OpenDirect(ctx context.Context, username, peer string) (chatName string, err error)
+
+	// Deletes chat with specified name, disconnecting all its members.
+	Delete(ctx context.Context, chatName string) error
+
+	// Makes chat with specified name read-only, disconnecting all its members.
+	// Chat history is kept.
+	Archive(ctx context.Context, chatName string) error
+
+	// Adds provided client to chat with provided chat name
+	// if client's user is allowed to join it. Messages with sequence numbers
+	// greater than lastSeq are replayed to the client, or the latest ones
+	// if lastSeq is zero.
+	AddClient(ctx context.Context, client Client, chatName string, lastSeq uint64) error
+
+	// Gets sorted usernames of users currently in chat with provided chat name.
+	Members(ctx context.Context, chatName string) ([]string, error)
+
+	// Gets statistics of currently loaded chats.
+	Stats() ChatStats
+}
 
-	// Lists all existing chats.
-	List() (chatNames []string, err error)
+type ChatStats struct {
+	// Number of chats loaded in memory.
+	LiveChats int
 
-	// Adds provided client to chat with provided chat name.
-	AddClient(client Client, chatName string) error
+	// Number of goroutines run by loaded chats.
+	ChatGoroutines int64
 }
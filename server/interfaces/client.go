@@ -1,5 +1,17 @@
 package interfaces
 
+import "github.com/shkotk/gochat/common/apimodels/events"
+
+// WebSocket close codes (RFC 6455) clients are closed with.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	ClosePolicyViolation = 1008
+	CloseServiceRestart  = 1012
+	CloseKicked          = events.CloseKicked
+	CloseFlooding        = events.CloseFlooding
+)
+
 type Client interface {
 	// Gets client identifier.
 	ID() string
@@ -7,9 +19,14 @@ type Client interface {
 	// Gets incoming events channel.
 	In() <-chan any
 
-	// Gets outgoing events channel.
-	Out() chan<- any
+	// Queues event to be sent to client without blocking.
+	// Events are sent in the same order they're queued.
+	Send(event any)
 
 	// Gets channel to be signalled when client is done.
 	Done() <-chan struct{}
+
+	// Closes client connection with provided close code and text once already
+	// queued events are sent.
+	Close(code int, text string)
 }
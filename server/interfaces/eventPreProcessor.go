@@ -1,6 +1,21 @@
 package interfaces
 
 type EventPreProcessor interface {
-	// Pre-process incoming event.
-	PreProcess(event any, producer Client) error
+	// Pre-process incoming event. Returns events to pass to chat, which may be
+	// the same event, several events produced from it or none.
+	PreProcess(event any, producer Client) ([]any, error)
+}
+
+// Single step of event pre-processing. Processors are chained, each one gets
+// events returned by the previous one.
+type EventProcessor interface {
+	// Unique name of the processor, used to enable it in chat configuration.
+	Name() string
+
+	// Whether processor applies to all chats regardless of their configuration.
+	Required() bool
+
+	// Processes incoming event, which can be mutated in place. Returns events
+	// to pass further or error if event should be rejected.
+	Process(event any, producer Client) ([]any, error)
 }
@@ -0,0 +1,9 @@
+package interfaces
+
+import "context"
+
+// Delivers events to users regardless of chats they are connected to.
+type Notifier interface {
+	// Sends event to all clients of the user connected to any server instance.
+	Notify(ctx context.Context, username string, event any) error
+}
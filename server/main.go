@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -17,6 +22,7 @@ import (
 	"github.com/shkotk/gochat/server/models"
 	"github.com/shkotk/gochat/server/repositories"
 	"github.com/shkotk/gochat/server/services"
+	"github.com/shkotk/gochat/server/websocket"
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -34,32 +40,105 @@ func main() {
 		v.RegisterValidation("name", validation.IsValidName)
 	}
 
-	err := InitializeRouter(cfg).RunTLS(
-		fmt.Sprintf(":%d", cfg.Port),
-		cfg.TLS.CertPath,
-		cfg.TLS.KeyPath)
-	if err != nil {
-		log.Fatalf("error running roter: %s", err)
+	app := InitializeApplication(cfg)
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Port),
+		Handler: app.router,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServeTLS(cfg.TLS.CertPath, cfg.TLS.KeyPath)
+	}()
+
+	select {
+	case err := <-serveErr:
+		log.Fatalf("error running server: %s", err)
+	case <-ctx.Done():
+		stop() // let repeated signal terminate the process immediately
+	}
+
+	app.logger.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := app.shutdown(shutdownCtx, server); err != nil {
+		app.logger.WithError(err).Error("Failed to shut down gracefully")
+	}
+}
+
+type application struct {
+	router         *gin.Engine
+	chatManager    *services.ChatManager
+	clientRegistry *services.ClientRegistry
+	logger         *logrus.Logger
+}
+
+// used in wire.go
+func newApplication(
+	router *gin.Engine,
+	chatManager *services.ChatManager,
+	clientRegistry *services.ClientRegistry,
+	logger *logrus.Logger,
+) *application {
+	return &application{router, chatManager, clientRegistry, logger}
+}
+
+// Stops accepting new connections, stops chats and closes connected clients.
+func (a *application) shutdown(ctx context.Context, server *http.Server) error {
+	err := server.Shutdown(ctx)
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
 	}
+
+	return errors.Join(
+		err,
+		a.chatManager.Shutdown(ctx),
+		a.clientRegistry.Shutdown(ctx),
+	)
 }
 
 // used in wire.go
 var servicesSet = wire.NewSet(
 	setupLogger,
 	setupDB,
+	setupBackplane,
 	services.NewJWTManager,
 	repositories.NewUserRepository,
+	repositories.NewChatRepository,
+	repositories.NewChatMemberRepository,
+	repositories.NewChatInviteRepository,
+	repositories.NewMessageRepository,
+	repositories.NewReactionRepository,
+	repositories.NewNotificationRepository,
 
 	wire.Bind(new(interfaces.ChatManager), new(*services.ChatManager)),
 	services.NewChatManager,
 
-	wire.Bind(new(interfaces.EventPreProcessor), new(*services.EventPreProcessor)),
-	services.NewEventPreProcessor,
+	wire.Bind(new(interfaces.EventPreProcessor), new(*services.EventProcessorChain)),
+	services.NewEventProcessorChain,
+	setupEventProcessors,
+	services.NewEventRateLimiter,
+	services.NewEventValidator,
+	services.NewWordFilter,
+	services.NewMentionParser,
+	services.NewCommandRegistry,
+	services.NewRequestRateLimiter,
+
+	services.NewClientRegistry,
+	wire.Bind(new(interfaces.Notifier), new(*services.ClientRegistry)),
+	websocket.NewClientFactory,
 
 	controllers.NewUserController,
 	controllers.NewChatController,
+	controllers.NewNotificationController,
+	controllers.NewDiagnosticsController,
 
 	setupRouter,
+	newApplication,
 )
 
 func setupLogger(cfg config.Config) *logrus.Logger {
@@ -97,7 +176,16 @@ func setupDB(cfg config.Config, logger *logrus.Logger) *gorm.DB {
 		logger.WithError(err).Fatal("Can't connect to DB")
 	}
 
-	err = db.AutoMigrate(models.User{}) // TODO add migrations?
+	err = db.AutoMigrate( // TODO add migrations?
+		models.User{},
+		models.Chat{},
+		models.ChatMember{},
+		models.ChatInvite{},
+		models.Message{},
+		models.Reaction{},
+		models.Notification{},
+		models.BackplanePayload{},
+	)
 	if err != nil {
 		logger.WithError(err).Fatal("Can't apply automatic migration")
 	}
@@ -105,30 +193,92 @@ func setupDB(cfg config.Config, logger *logrus.Logger) *gorm.DB {
 	return db
 }
 
+func setupBackplane(
+	cfg config.Config,
+	db *gorm.DB,
+	logger *logrus.Logger,
+) interfaces.Backplane {
+	if cfg.Backplane == config.BackplanePostgres {
+		return services.NewPostgresBackplane(cfg, db, logger)
+	}
+
+	return services.NewMemoryBackplane(logger)
+}
+
+// Lists event processors in order they're applied to incoming events.
+func setupEventProcessors(
+	rateLimiter *services.EventRateLimiter,
+	validator *services.EventValidator,
+	commands *services.CommandRegistry,
+	wordFilter *services.WordFilter,
+	mentionParser *services.MentionParser,
+) []interfaces.EventProcessor {
+	return []interfaces.EventProcessor{
+		rateLimiter,
+		validator,
+		commands,
+		wordFilter,
+		mentionParser,
+	}
+}
+
 func setupRouter(
 	cfg config.Config,
 	logger *logrus.Logger,
 	jwtManager *services.JWTManager,
+	requestRateLimiter *services.RequestRateLimiter,
 	userController *controllers.UserController,
 	chatController *controllers.ChatController,
+	notificationController *controllers.NotificationController,
+	diagnosticsController *controllers.DiagnosticsController,
 ) *gin.Engine {
 	if !cfg.Debug {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	router := gin.New()
-	router.SetTrustedProxies(nil)
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.WithError(err).Fatal("Can't set trusted proxies")
+	}
 	router.Use(middleware.Logger(logger), middleware.Recovery(logger))
 	jwtRouterGroup := router.Group("", middleware.JWT(jwtManager))
+	rateLimit := middleware.RateLimit(requestRateLimiter)
+	userRateLimit := middleware.UserRateLimit(requestRateLimiter)
 
 	router.GET("/user/exists/:username", userController.Exists)
-	router.POST("/user/register", userController.Register)
-	router.GET("/token/get", userController.GetToken)
+	router.POST("/user/register", rateLimit, userController.Register)
+	router.GET("/token/get", rateLimit, userController.GetToken)
 	jwtRouterGroup.GET("/token/refresh", userController.RefreshToken)
 
-	jwtRouterGroup.POST("/chat/create/:chatName", chatController.Create)
+	jwtRouterGroup.POST("/chat/create/:chatName", userRateLimit, chatController.Create)
 	jwtRouterGroup.GET("/chat/list", chatController.List)
+	jwtRouterGroup.DELETE("/chat/delete/:chatName", chatController.Delete)
+	jwtRouterGroup.POST("/chat/archive/:chatName", chatController.Archive)
 	jwtRouterGroup.GET("/chat/join/:chatName", chatController.Join)
+	jwtRouterGroup.GET("/chat/connect", chatController.Connect)
+	jwtRouterGroup.GET("/chat/history/:chatName", chatController.History)
+	jwtRouterGroup.GET("/chat/members/:chatName", chatController.Members)
+	jwtRouterGroup.GET("/chat/thread/:chatName/:messageID", chatController.Thread)
+	jwtRouterGroup.GET("/chat/roles/:chatName", chatController.Roles)
+	jwtRouterGroup.PUT("/chat/roles/:chatName/:username/:role", chatController.GrantRole)
+	jwtRouterGroup.DELETE("/chat/roles/:chatName/:username", chatController.RevokeRole)
+	jwtRouterGroup.GET("/chat/processors/:chatName", chatController.Processors)
+	jwtRouterGroup.PUT("/chat/processors/:chatName", chatController.ConfigureProcessors)
+	jwtRouterGroup.POST("/chat/invite/:chatName", chatController.Invite)
+	jwtRouterGroup.POST("/invite/redeem/:token", chatController.RedeemInvite)
+
+	jwtRouterGroup.POST("/dm/open/:username", chatController.OpenDirect)
+	jwtRouterGroup.GET("/dm/list", chatController.ListDirect)
+	jwtRouterGroup.GET("/dm/join/:username", chatController.JoinDirect)
+	jwtRouterGroup.GET("/dm/history/:username", chatController.DirectHistory)
+	jwtRouterGroup.GET("/dm/members/:username", chatController.DirectMembers)
+	jwtRouterGroup.GET("/dm/thread/:username/:messageID", chatController.DirectThread)
+
+	jwtRouterGroup.GET("/notification/list", notificationController.List)
+	jwtRouterGroup.POST("/notification/read", notificationController.MarkRead)
+	jwtRouterGroup.DELETE("/notification/clear", notificationController.Clear)
+
+	jwtRouterGroup.GET("/diagnostics", diagnosticsController.Get)
 
 	return router
 }
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shkotk/gochat/common/apimodels/responses"
+	"github.com/shkotk/gochat/server/services"
+)
+
+// Rejects requests to the endpoint coming from the same IP address too often.
+func RateLimit(limiter *services.RequestRateLimiter) gin.HandlerFunc {
+	return rateLimit(limiter, func(ctx *gin.Context) string { return ctx.ClientIP() })
+}
+
+// Rejects requests to the endpoint made by the same user too often.
+// Must be used after JWT middleware.
+func UserRateLimit(limiter *services.RequestRateLimiter) gin.HandlerFunc {
+	return rateLimit(limiter, func(ctx *gin.Context) string {
+		return ctx.MustGet(UserClaimsKey).(services.UserClaims).Username
+	})
+}
+
+func rateLimit(
+	limiter *services.RequestRateLimiter, key func(ctx *gin.Context) string,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !limiter.Allow(ctx.FullPath() + " " + key(ctx)) {
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, responses.Error{
+				Error: "Too many requests, try again later",
+			})
+			return
+		}
+
+		ctx.Next()
+	}
+}
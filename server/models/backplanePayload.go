@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Backplane payload too large to be sent within Postgres notification,
+// which carries its ID instead.
+type BackplanePayload struct {
+	ID        string    `gorm:"primaryKey;default:null"`
+	Payload   []byte    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null;index"`
+}
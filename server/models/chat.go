@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type Chat struct {
+	Name      string    `gorm:"primaryKey;default:null"`
+	Owner     string    `gorm:"not null;default:''"`
+	Archived  bool      `gorm:"not null;default:false"`
+	Private   bool      `gorm:"not null;default:false"`
+	Direct    bool      `gorm:"not null;default:false"`
+	Topic     string    `gorm:"not null;default:''"`
+	CreatedAt time.Time `gorm:"not null"`
+
+	// Sequence number of the latest message in chat.
+	LastSeq uint64 `gorm:"not null;default:0"`
+
+	// Names of optional event processors applied to chat, defaults are applied if nil.
+	Processors []string `gorm:"serializer:json"`
+
+	Members []ChatMember `gorm:"foreignKey:ChatName;constraint:OnDelete:CASCADE"`
+	Invites []ChatInvite `gorm:"foreignKey:ChatName;constraint:OnDelete:CASCADE"`
+}
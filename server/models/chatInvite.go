@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type ChatInvite struct {
+	Token     string    `gorm:"primaryKey;default:null"`
+	ChatName  string    `gorm:"not null;default:null;index"`
+	CreatedBy string    `gorm:"not null;default:null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	MaxUses   int       `gorm:"not null"` // unlimited if zero
+	Uses      int       `gorm:"not null"`
+}
+
+// Checks whether invite can still be redeemed at provided time.
+func (i ChatInvite) IsValid(now time.Time) bool {
+	return now.Before(i.ExpiresAt) && (i.MaxUses == 0 || i.Uses < i.MaxUses)
+}
@@ -0,0 +1,20 @@
+package models
+
+type Role string
+
+const (
+	RoleOwner     Role = "owner"
+	RoleModerator Role = "moderator"
+	RoleMember    Role = "member"
+)
+
+// Checks whether role allows moderating chat members and their messages.
+func (r Role) CanModerate() bool {
+	return r == RoleOwner || r == RoleModerator
+}
+
+type ChatMember struct {
+	ChatName string `gorm:"primaryKey;default:null"`
+	Username string `gorm:"primaryKey;default:null"`
+	Role     Role   `gorm:"not null;default:null"`
+}
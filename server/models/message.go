@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type Message struct {
+	ID       string    `gorm:"primaryKey;default:null"`
+	ChatName string    `gorm:"not null;default:null;index:idx_messages_chat_seq"`
+	Seq      uint64    `gorm:"not null;index:idx_messages_chat_seq"`
+	Producer string    `gorm:"not null;default:null"`
+	Text     string    `gorm:"not null"`
+	Time     time.Time `gorm:"not null"`
+	ReplyTo  string    `gorm:"not null;default:'';index"`
+	Mentions []string  `gorm:"serializer:json"`
+	Action   bool      `gorm:"not null;default:false"`
+	Edited   bool      `gorm:"not null;default:false"`
+	Deleted  bool      `gorm:"not null;default:false"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type NotificationKind string
+
+const (
+	// User was mentioned in a chat they weren't connected to.
+	NotificationMention NotificationKind = "mention"
+	// User got a direct message while not connected to direct chat.
+	NotificationDirect NotificationKind = "direct"
+)
+
+// Record of a message user has missed.
+type Notification struct {
+	ID        string           `gorm:"primaryKey;default:null"`
+	Username  string           `gorm:"not null;default:null;index"`
+	Kind      NotificationKind `gorm:"not null;default:null"`
+	ChatName  string           `gorm:"not null;default:null"`
+	MessageID string           `gorm:"not null;default:null"`
+	Producer  string           `gorm:"not null;default:null"`
+	Text      string           `gorm:"not null"`
+	Time      time.Time        `gorm:"not null"`
+	Read      bool             `gorm:"not null;default:false"`
+}
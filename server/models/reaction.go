@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Reaction of a user to a message. User can react to a message with each emoji once.
+type Reaction struct {
+	MessageID string    `gorm:"primaryKey;default:null"`
+	Username  string    `gorm:"primaryKey;default:null"`
+	Emoji     string    `gorm:"primaryKey;default:null"`
+	ChatName  string    `gorm:"not null;default:null;index"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+// Number of users who reacted to a message with the same emoji.
+type ReactionCount struct {
+	MessageID string
+	Emoji     string
+	Count     int
+}
@@ -0,0 +1,230 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shkotk/gochat/server/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type ChatRepository struct {
+	logger *logrus.Logger
+	db     *gorm.DB
+}
+
+func NewChatRepository(logger *logrus.Logger, db *gorm.DB) *ChatRepository {
+	return &ChatRepository{logger, db}
+}
+
+func (r *ChatRepository) Create(ctx context.Context, chat models.Chat) error {
+	err := r.db.WithContext(ctx).Create(&chat).Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "create_chat",
+				"record_id": chat.Name,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
+
+// Returns chat corresponding to provided name or nil if it does not exist.
+func (r *ChatRepository) Get(ctx context.Context, chatName string) (*models.Chat, error) {
+	chat := &models.Chat{}
+	err := r.db.WithContext(ctx).First(chat, "name = ?", chatName).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "get_chat",
+				"record_id": chatName,
+			}).
+			Error()
+		return nil, err
+	}
+
+	return chat, nil
+}
+
+// Returns all stored chats ordered by creation time.
+func (r *ChatRepository) GetAll(ctx context.Context) ([]models.Chat, error) {
+	chats := []models.Chat{}
+	err := r.db.WithContext(ctx).Order("created_at").Find(&chats).Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithField("action", "get_all_chats").
+			Error()
+		return nil, err
+	}
+
+	return chats, nil
+}
+
+func (r *ChatRepository) Exists(ctx context.Context, chatName string) (exists bool, err error) {
+	err = r.db.WithContext(ctx).
+		Model(&models.Chat{}).
+		Select("count(*) > 0").
+		Where("name = ?", chatName).
+		Find(&exists).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "check_if_chat_exists",
+				"record_id": chatName,
+			}).
+			Error()
+	}
+
+	return
+}
+
+// Returns group chats visible to the user ordered by creation time: all public
+// chats and private chats the user is a member of.
+func (r *ChatRepository) GetVisible(ctx context.Context, username string) ([]models.Chat, error) {
+	chats := []models.Chat{}
+	err := r.db.WithContext(ctx).
+		Where("NOT direct").
+		Where("NOT private OR EXISTS (?)", r.membershipQuery(username)).
+		Order("created_at").
+		Find(&chats).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "get_visible_chats",
+				"record_id": username,
+			}).
+			Error()
+		return nil, err
+	}
+
+	return chats, nil
+}
+
+// Returns direct chats of the user ordered by creation time.
+func (r *ChatRepository) GetDirect(ctx context.Context, username string) ([]models.Chat, error) {
+	chats := []models.Chat{}
+	err := r.db.WithContext(ctx).
+		Where("direct AND EXISTS (?)", r.membershipQuery(username)).
+		Order("created_at").
+		Find(&chats).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "get_direct_chats",
+				"record_id": username,
+			}).
+			Error()
+		return nil, err
+	}
+
+	return chats, nil
+}
+
+// Deletes chat along with its members, messages and notifications about them.
+func (r *ChatRepository) Delete(ctx context.Context, chatName string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Delete(&models.Reaction{}, "chat_name = ?", chatName).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Delete(&models.Message{}, "chat_name = ?", chatName).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Delete(&models.Notification{}, "chat_name = ?", chatName).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.Chat{}, "name = ?", chatName).Error
+	})
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "delete_chat",
+				"record_id": chatName,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
+
+func (r *ChatRepository) SetArchived(ctx context.Context, chatName string, archived bool) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.Chat{}).
+		Where("name = ?", chatName).
+		Update("archived", archived).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "set_chat_archived",
+				"record_id": chatName,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
+
+func (r *ChatRepository) SetTopic(ctx context.Context, chatName, topic string) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.Chat{}).
+		Where("name = ?", chatName).
+		Update("topic", topic).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "set_chat_topic",
+				"record_id": chatName,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
+
+// Stores names of optional event processors applied to chat, nil resets them to defaults.
+func (r *ChatRepository) SetProcessors(ctx context.Context, chatName string, processors []string) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.Chat{Name: chatName}).
+		Select("processors").
+		Updates(&models.Chat{Processors: processors}).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "set_chat_processors",
+				"record_id": chatName,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
+
+// Builds subquery selecting membership of the user in chat from outer query.
+func (r *ChatRepository) membershipQuery(username string) *gorm.DB {
+	return r.db.Model(&models.ChatMember{}).
+		Select("1").
+		Where("chat_members.chat_name = chats.name AND chat_members.username = ?", username)
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shkotk/gochat/server/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ChatInviteRepository struct {
+	logger *logrus.Logger
+	db     *gorm.DB
+}
+
+func NewChatInviteRepository(logger *logrus.Logger, db *gorm.DB) *ChatInviteRepository {
+	return &ChatInviteRepository{logger, db}
+}
+
+func (r *ChatInviteRepository) Create(ctx context.Context, invite models.ChatInvite) error {
+	err := r.db.WithContext(ctx).Create(&invite).Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "create_chat_invite",
+				"record_id": invite.ChatName,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
+
+// Redeems invite with provided token, making user a member of the invite's chat.
+// Existing role of the user is kept. Returns redeemed invite or nil if there is
+// no valid invite with provided token.
+func (r *ChatInviteRepository) Redeem(
+	ctx context.Context, token, username string,
+) (*models.ChatInvite, error) {
+	invite := &models.ChatInvite{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(invite, "token = ?", token).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if !invite.IsValid(time.Now()) {
+			return gorm.ErrRecordNotFound
+		}
+
+		invite.Uses++
+		err = tx.Model(invite).Update("uses", invite.Uses).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.ChatMember{
+				ChatName: invite.ChatName,
+				Username: username,
+				Role:     models.RoleMember,
+			}).
+			Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "redeem_chat_invite",
+				"record_id": token,
+			}).
+			Error()
+		return nil, err
+	}
+
+	return invite, nil
+}
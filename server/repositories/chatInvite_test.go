@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shkotk/gochat/server/models"
+	"github.com/sirupsen/logrus"
+)
+
+func (s *DBTestSuite) TestChatInvite_Create_ValidInvite_AddsRecord() {
+	s.testDB.Create(&models.Chat{Name: "secret", Private: true})
+	chatInviteRepository := NewChatInviteRepository(logrus.StandardLogger(), s.testDB)
+
+	err := chatInviteRepository.Create(context.Background(), models.ChatInvite{
+		Token:     uuid.NewString(),
+		ChatName:  "secret",
+		CreatedBy: "michael",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+
+	s.Nil(err)
+
+	var count int64
+	s.testDB.Model(&models.ChatInvite{}).Count(&count)
+	s.Equal(int64(1), count)
+}
+
+func (s *DBTestSuite) TestChatInvite_Redeem_ValidInvite_AddsMemberAndCountsUse() {
+	token := uuid.NewString()
+	s.testDB.Create(&models.Chat{
+		Name:    "secret",
+		Private: true,
+		Invites: []models.ChatInvite{{
+			Token:     token,
+			CreatedBy: "michael",
+			ExpiresAt: time.Now().Add(time.Hour),
+			MaxUses:   2,
+		}},
+	})
+	chatInviteRepository := NewChatInviteRepository(logrus.StandardLogger(), s.testDB)
+
+	invite, err := chatInviteRepository.Redeem(context.Background(), token, "jim")
+
+	s.Nil(err)
+	s.Equal("secret", invite.ChatName)
+	s.Equal(1, invite.Uses)
+
+	member := models.ChatMember{}
+	s.testDB.First(&member, "chat_name = ? AND username = ?", "secret", "jim")
+	s.Equal(models.RoleMember, member.Role)
+}
+
+func (s *DBTestSuite) TestChatInvite_Redeem_ExistingMember_KeepsRole() {
+	token := uuid.NewString()
+	s.testDB.Create(&models.Chat{
+		Name:    "secret",
+		Private: true,
+		Members: []models.ChatMember{{Username: "dwight", Role: models.RoleModerator}},
+		Invites: []models.ChatInvite{{
+			Token:     token,
+			CreatedBy: "michael",
+			ExpiresAt: time.Now().Add(time.Hour),
+		}},
+	})
+	chatInviteRepository := NewChatInviteRepository(logrus.StandardLogger(), s.testDB)
+
+	invite, err := chatInviteRepository.Redeem(context.Background(), token, "dwight")
+
+	s.Nil(err)
+	s.NotNil(invite)
+
+	member := models.ChatMember{}
+	s.testDB.First(&member, "chat_name = ? AND username = ?", "secret", "dwight")
+	s.Equal(models.RoleModerator, member.Role)
+}
+
+func (s *DBTestSuite) TestChatInvite_Redeem_InvalidInvite_ReturnsNil() {
+	expiredToken := uuid.NewString()
+	exhaustedToken := uuid.NewString()
+	s.testDB.Create(&models.Chat{
+		Name:    "secret",
+		Private: true,
+		Invites: []models.ChatInvite{
+			{
+				Token:     expiredToken,
+				CreatedBy: "michael",
+				ExpiresAt: time.Now().Add(-time.Minute),
+			},
+			{
+				Token:     exhaustedToken,
+				CreatedBy: "michael",
+				ExpiresAt: time.Now().Add(time.Hour),
+				MaxUses:   1,
+				Uses:      1,
+			},
+		},
+	})
+
+	tests := []struct {
+		label string
+		token string
+	}{
+		{"missing", uuid.NewString()},
+		{"expired", expiredToken},
+		{"exhausted", exhaustedToken},
+	}
+
+	chatInviteRepository := NewChatInviteRepository(logrus.StandardLogger(), s.testDB)
+
+	for _, test := range tests {
+		s.Run(test.label, func() {
+			invite, err := chatInviteRepository.Redeem(context.Background(), test.token, "jim")
+
+			s.Nil(err)
+			s.Nil(invite)
+		})
+	}
+
+	var count int64
+	s.testDB.Model(&models.ChatMember{}).Count(&count)
+	s.Zero(count)
+}
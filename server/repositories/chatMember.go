@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shkotk/gochat/server/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ChatMemberRepository struct {
+	logger *logrus.Logger
+	db     *gorm.DB
+}
+
+func NewChatMemberRepository(logger *logrus.Logger, db *gorm.DB) *ChatMemberRepository {
+	return &ChatMemberRepository{logger, db}
+}
+
+// Creates chat member or updates role of existing one.
+func (r *ChatMemberRepository) Save(ctx context.Context, member models.ChatMember) error {
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "chat_name"}, {Name: "username"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role"}),
+		}).
+		Create(&member).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "save_chat_member",
+				"record_id": member.ChatName + "/" + member.Username,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
+
+// Returns role of the user in the chat or empty role if user is not a chat member.
+func (r *ChatMemberRepository) GetRole(
+	ctx context.Context, chatName, username string,
+) (models.Role, error) {
+	member := &models.ChatMember{}
+	err := r.db.WithContext(ctx).
+		First(member, "chat_name = ? AND username = ?", chatName, username).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "get_chat_member_role",
+				"record_id": chatName + "/" + username,
+			}).
+			Error()
+		return "", err
+	}
+
+	return member.Role, nil
+}
+
+// Returns all members of the chat ordered by username.
+func (r *ChatMemberRepository) GetAll(
+	ctx context.Context, chatName string,
+) ([]models.ChatMember, error) {
+	members := []models.ChatMember{}
+	err := r.db.WithContext(ctx).
+		Where("chat_name = ?", chatName).
+		Order("username").
+		Find(&members).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "get_all_chat_members",
+				"record_id": chatName,
+			}).
+			Error()
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (r *ChatMemberRepository) Delete(ctx context.Context, chatName, username string) error {
+	err := r.db.WithContext(ctx).
+		Delete(&models.ChatMember{}, "chat_name = ? AND username = ?", chatName, username).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "delete_chat_member",
+				"record_id": chatName + "/" + username,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
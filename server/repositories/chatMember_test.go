@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/shkotk/gochat/server/models"
+	"github.com/sirupsen/logrus"
+)
+
+func (s *DBTestSuite) TestChatMember_Save_NewMember_AddsRecord() {
+	s.testDB.Create(&models.Chat{Name: "general"})
+	chatMemberRepository := NewChatMemberRepository(logrus.StandardLogger(), s.testDB)
+
+	err := chatMemberRepository.Save(context.Background(), models.ChatMember{
+		ChatName: "general",
+		Username: "stanley",
+		Role:     models.RoleModerator,
+	})
+
+	s.Nil(err)
+
+	members := []models.ChatMember{}
+	s.testDB.Find(&members)
+	s.Len(members, 1)
+	s.Equal(models.RoleModerator, members[0].Role)
+}
+
+func (s *DBTestSuite) TestChatMember_Save_ExistingMember_UpdatesRole() {
+	s.testDB.Create(&models.Chat{
+		Name:    "general",
+		Members: []models.ChatMember{{Username: "stanley", Role: models.RoleModerator}},
+	})
+	chatMemberRepository := NewChatMemberRepository(logrus.StandardLogger(), s.testDB)
+
+	err := chatMemberRepository.Save(context.Background(), models.ChatMember{
+		ChatName: "general",
+		Username: "stanley",
+		Role:     models.RoleMember,
+	})
+
+	s.Nil(err)
+
+	members := []models.ChatMember{}
+	s.testDB.Find(&members)
+	s.Len(members, 1)
+	s.Equal(models.RoleMember, members[0].Role)
+}
+
+func (s *DBTestSuite) TestChatMember_Save_MissingChat_ReturnsError() {
+	chatMemberRepository := NewChatMemberRepository(logrus.StandardLogger(), s.testDB)
+
+	err := chatMemberRepository.Save(context.Background(), models.ChatMember{
+		ChatName: "missing",
+		Username: "stanley",
+		Role:     models.RoleMember,
+	})
+
+	s.ErrorContains(err, "violates foreign key constraint")
+}
+
+func (s *DBTestSuite) TestChatMember_GetRole_PopulatedChatMembersTable_ReturnsExpectedResult() {
+	s.testDB.Create(&models.Chat{
+		Name:  "general",
+		Owner: "michael",
+		Members: []models.ChatMember{
+			{Username: "michael", Role: models.RoleOwner},
+			{Username: "dwight", Role: models.RoleModerator},
+		},
+	})
+
+	tests := []struct {
+		username     string
+		expectedRole models.Role
+	}{
+		{"michael", models.RoleOwner},
+		{"dwight", models.RoleModerator},
+		{"toby", ""},
+	}
+
+	chatMemberRepository := NewChatMemberRepository(logrus.StandardLogger(), s.testDB)
+
+	for _, test := range tests {
+		s.Run(test.username, func() {
+			actual, err := chatMemberRepository.GetRole(
+				context.Background(), "general", test.username)
+
+			s.Nil(err)
+			s.Equal(test.expectedRole, actual)
+		})
+	}
+}
+
+func (s *DBTestSuite) TestChatMember_GetAll_PopulatedChatMembersTable_ReturnsChatMembers() {
+	s.testDB.Create([]models.Chat{
+		{
+			Name: "general",
+			Members: []models.ChatMember{
+				{Username: "michael", Role: models.RoleOwner},
+				{Username: "dwight", Role: models.RoleModerator},
+			},
+		},
+		{
+			Name:    "other",
+			Members: []models.ChatMember{{Username: "toby", Role: models.RoleOwner}},
+		},
+	})
+
+	chatMemberRepository := NewChatMemberRepository(logrus.StandardLogger(), s.testDB)
+
+	members, err := chatMemberRepository.GetAll(context.Background(), "general")
+
+	s.Nil(err)
+	s.Len(members, 2)
+	s.Equal("dwight", members[0].Username)
+	s.Equal("michael", members[1].Username)
+}
+
+func (s *DBTestSuite) TestChatMember_Delete_ExistingMember_RemovesRecord() {
+	s.testDB.Create(&models.Chat{
+		Name:    "general",
+		Members: []models.ChatMember{{Username: "dwight", Role: models.RoleModerator}},
+	})
+	chatMemberRepository := NewChatMemberRepository(logrus.StandardLogger(), s.testDB)
+
+	err := chatMemberRepository.Delete(context.Background(), "general", "dwight")
+
+	s.Nil(err)
+
+	var count int64
+	s.testDB.Model(&models.ChatMember{}).Count(&count)
+	s.Zero(count)
+}
@@ -0,0 +1,320 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/shkotk/gochat/server/models"
+	"github.com/sirupsen/logrus"
+)
+
+func (s *DBTestSuite) TestChat_Create_CancelledContext_ReturnsError() {
+	chatRepository := NewChatRepository(logrus.StandardLogger(), s.testDB)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := chatRepository.Create(ctx, models.Chat{Name: "chat"})
+
+	s.Equal(context.Canceled, err)
+}
+
+func (s *DBTestSuite) TestChat_Create_InvalidChat_ReturnsError() {
+	s.testDB.Create(&models.Chat{Name: "shouldBeUnique"})
+
+	tests := []struct {
+		label         string
+		chat          models.Chat
+		expectedError string
+	}{
+		{
+			label:         "missing name",
+			chat:          models.Chat{},
+			expectedError: `null value in column "name" of relation "chats" violates not-null constraint`,
+		},
+		{
+			label:         "name duplicate",
+			chat:          models.Chat{Name: "shouldBeUnique"},
+			expectedError: `duplicate key value violates unique constraint`,
+		},
+	}
+
+	chatRepository := NewChatRepository(logrus.StandardLogger(), s.testDB)
+
+	for _, test := range tests {
+		s.Run(test.label, func() {
+			err := chatRepository.Create(context.Background(), test.chat)
+
+			s.ErrorContains(err, test.expectedError)
+		})
+	}
+}
+
+func (s *DBTestSuite) TestChat_Create_ValidChat_AddsRecord() {
+	chatRepository := NewChatRepository(logrus.StandardLogger(), s.testDB)
+
+	err := chatRepository.Create(context.Background(), models.Chat{Name: "general"})
+
+	s.Nil(err)
+
+	chats := []models.Chat{}
+	s.testDB.Find(&chats)
+	s.Len(chats, 1)
+	s.Equal("general", chats[0].Name)
+	s.False(chats[0].CreatedAt.IsZero())
+}
+
+func (s *DBTestSuite) TestChat_GetAll_CancelledContext_ReturnsError() {
+	chatRepository := NewChatRepository(logrus.StandardLogger(), s.testDB)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := chatRepository.GetAll(ctx)
+
+	s.Equal(context.Canceled, err)
+}
+
+func (s *DBTestSuite) TestChat_GetAll_PopulatedChatsTable_ReturnsChatsInCreationOrder() {
+	now := time.Now()
+	s.testDB.Create([]models.Chat{
+		{Name: "second", CreatedAt: now},
+		{Name: "first", CreatedAt: now.Add(-time.Hour)},
+	})
+
+	chatRepository := NewChatRepository(logrus.StandardLogger(), s.testDB)
+
+	chats, err := chatRepository.GetAll(context.Background())
+
+	s.Nil(err)
+	s.Len(chats, 2)
+	s.Equal("first", chats[0].Name)
+	s.Equal("second", chats[1].Name)
+}
+
+func (s *DBTestSuite) TestChat_Exists_PopulatedChatsTable_ReturnsExpectedResult() {
+	s.testDB.Create([]models.Chat{{Name: "general"}, {Name: "random"}})
+
+	tests := []struct {
+		chatName       string
+		expectedResult bool
+	}{
+		{"general", true},
+		{"missing", false},
+		{"random", true},
+	}
+
+	chatRepository := NewChatRepository(logrus.StandardLogger(), s.testDB)
+
+	for _, test := range tests {
+		s.Run(test.chatName, func() {
+			actual, err := chatRepository.Exists(context.Background(), test.chatName)
+
+			s.Nil(err)
+			s.Equal(test.expectedResult, actual, "got wrong result for %s", test.chatName)
+		})
+	}
+}
+
+func (s *DBTestSuite) TestChat_Create_ChatWithMembers_AddsMemberRecords() {
+	chatRepository := NewChatRepository(logrus.StandardLogger(), s.testDB)
+
+	err := chatRepository.Create(context.Background(), models.Chat{
+		Name:    "general",
+		Owner:   "michael",
+		Members: []models.ChatMember{{Username: "michael", Role: models.RoleOwner}},
+	})
+
+	s.Nil(err)
+
+	members := []models.ChatMember{}
+	s.testDB.Find(&members)
+	s.Len(members, 1)
+	s.Equal("general", members[0].ChatName)
+	s.Equal("michael", members[0].Username)
+	s.Equal(models.RoleOwner, members[0].Role)
+}
+
+func (s *DBTestSuite) TestChat_Get_PopulatedChatsTable_ReturnsExpectedResult() {
+	s.testDB.Create(&models.Chat{Name: "general", Owner: "michael"})
+
+	chatRepository := NewChatRepository(logrus.StandardLogger(), s.testDB)
+
+	chat, err := chatRepository.Get(context.Background(), "general")
+	s.Nil(err)
+	s.Equal("michael", chat.Owner)
+
+	chat, err = chatRepository.Get(context.Background(), "missing")
+	s.Nil(err)
+	s.Nil(chat)
+}
+
+func (s *DBTestSuite) TestChat_Delete_ExistingChat_RemovesChatWithMembersMessagesReactionsAndNotifications() {
+	s.testDB.Create([]models.Chat{
+		{
+			Name:    "general",
+			Members: []models.ChatMember{{Username: "michael", Role: models.RoleOwner}},
+		},
+		{Name: "other"},
+	})
+	s.testDB.Create([]models.Message{
+		{ID: "1", ChatName: "general", Seq: 1, Producer: "michael", Text: "hi", Time: time.Now()},
+		{ID: "2", ChatName: "other", Seq: 1, Producer: "michael", Text: "hi", Time: time.Now()},
+	})
+	s.testDB.Create([]models.Reaction{
+		{MessageID: "1", Username: "michael", Emoji: "tada", ChatName: "general"},
+		{MessageID: "2", Username: "michael", Emoji: "tada", ChatName: "other"},
+	})
+	s.testDB.Create([]models.Notification{
+		{ID: "1", Username: "jim", Kind: models.NotificationMention, ChatName: "general",
+			MessageID: "1", Producer: "michael", Text: "hi", Time: time.Now()},
+		{ID: "2", Username: "jim", Kind: models.NotificationMention, ChatName: "other",
+			MessageID: "2", Producer: "michael", Text: "hi", Time: time.Now()},
+	})
+
+	chatRepository := NewChatRepository(logrus.StandardLogger(), s.testDB)
+
+	err := chatRepository.Delete(context.Background(), "general")
+
+	s.Nil(err)
+
+	chats := []models.Chat{}
+	s.testDB.Find(&chats)
+	s.Len(chats, 1)
+	s.Equal("other", chats[0].Name)
+
+	var membersCount int64
+	s.testDB.Model(&models.ChatMember{}).Count(&membersCount)
+	s.Zero(membersCount)
+
+	messages := []models.Message{}
+	s.testDB.Find(&messages)
+	s.Len(messages, 1)
+	s.Equal("other", messages[0].ChatName)
+
+	reactions := []models.Reaction{}
+	s.testDB.Find(&reactions)
+	s.Len(reactions, 1)
+	s.Equal("other", reactions[0].ChatName)
+
+	notifications := []models.Notification{}
+	s.testDB.Find(&notifications)
+	s.Len(notifications, 1)
+	s.Equal("other", notifications[0].ChatName)
+}
+
+func (s *DBTestSuite) TestChat_SetArchived_ExistingChat_UpdatesRecord() {
+	s.testDB.Create(&models.Chat{Name: "general"})
+
+	chatRepository := NewChatRepository(logrus.StandardLogger(), s.testDB)
+
+	err := chatRepository.SetArchived(context.Background(), "general", true)
+
+	s.Nil(err)
+
+	chat := models.Chat{}
+	s.testDB.First(&chat, "name = ?", "general")
+	s.True(chat.Archived)
+}
+
+func (s *DBTestSuite) TestChat_GetVisible_PublicAndPrivateChats_ReturnsVisibleChats() {
+	now := time.Now()
+	s.testDB.Create([]models.Chat{
+		{Name: "public", CreatedAt: now.Add(-2 * time.Hour)},
+		{
+			Name:      "joined",
+			Private:   true,
+			CreatedAt: now.Add(-time.Hour),
+			Members:   []models.ChatMember{{Username: "jim", Role: models.RoleMember}},
+		},
+		{
+			Name:      "secret",
+			Private:   true,
+			CreatedAt: now,
+			Members:   []models.ChatMember{{Username: "michael", Role: models.RoleOwner}},
+		},
+	})
+
+	chatRepository := NewChatRepository(logrus.StandardLogger(), s.testDB)
+
+	chats, err := chatRepository.GetVisible(context.Background(), "jim")
+
+	s.Nil(err)
+	s.Len(chats, 2)
+	s.Equal("public", chats[0].Name)
+	s.Equal("joined", chats[1].Name)
+}
+
+func (s *DBTestSuite) TestChat_GetDirect_PopulatedChatsTable_ReturnsUserDirectChats() {
+	s.testDB.Create([]models.Chat{
+		{Name: "public"},
+		{
+			Name:    "dm:jim:pam",
+			Private: true,
+			Direct:  true,
+			Members: []models.ChatMember{
+				{Username: "jim", Role: models.RoleMember},
+				{Username: "pam", Role: models.RoleMember},
+			},
+		},
+		{
+			Name:    "dm:dwight:michael",
+			Private: true,
+			Direct:  true,
+			Members: []models.ChatMember{
+				{Username: "dwight", Role: models.RoleMember},
+				{Username: "michael", Role: models.RoleMember},
+			},
+		},
+	})
+
+	chatRepository := NewChatRepository(logrus.StandardLogger(), s.testDB)
+
+	direct, err := chatRepository.GetDirect(context.Background(), "jim")
+	s.Nil(err)
+	s.Len(direct, 1)
+	s.Equal("dm:jim:pam", direct[0].Name)
+
+	visible, err := chatRepository.GetVisible(context.Background(), "jim")
+	s.Nil(err)
+	s.Len(visible, 1)
+	s.Equal("public", visible[0].Name)
+}
+
+func (s *DBTestSuite) TestChat_SetProcessors_ExistingChat_UpdatesRecord() {
+	s.testDB.Create(&models.Chat{Name: "general"})
+
+	chatRepository := NewChatRepository(logrus.StandardLogger(), s.testDB)
+
+	chat, err := chatRepository.Get(context.Background(), "general")
+	s.Nil(err)
+	s.Nil(chat.Processors)
+
+	err = chatRepository.SetProcessors(context.Background(), "general", []string{"mentions"})
+	s.Nil(err)
+
+	chat, err = chatRepository.Get(context.Background(), "general")
+	s.Nil(err)
+	s.Equal([]string{"mentions"}, chat.Processors)
+
+	err = chatRepository.SetProcessors(context.Background(), "general", nil)
+	s.Nil(err)
+
+	chat, err = chatRepository.Get(context.Background(), "general")
+	s.Nil(err)
+	s.Nil(chat.Processors)
+}
+
+func (s *DBTestSuite) TestChat_SetTopic_ExistingChat_UpdatesRecord() {
+	s.testDB.Create(&models.Chat{Name: "general"})
+
+	chatRepository := NewChatRepository(logrus.StandardLogger(), s.testDB)
+
+	err := chatRepository.SetTopic(context.Background(), "general", "paper sales")
+
+	s.Nil(err)
+
+	chat := models.Chat{}
+	s.testDB.First(&chat, "name = ?", "general")
+	s.Equal("paper sales", chat.Topic)
+}
@@ -0,0 +1,237 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shkotk/gochat/server/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MessageRepository struct {
+	logger *logrus.Logger
+	db     *gorm.DB
+}
+
+func NewMessageRepository(logger *logrus.Logger, db *gorm.DB) *MessageRepository {
+	return &MessageRepository{logger, db}
+}
+
+// Stores provided message, assigning it the next sequence number of its chat.
+// Messages of a chat are stored one at a time, so sequence numbers follow the order
+// in which messages are stored.
+func (r *MessageRepository) Create(ctx context.Context, message *models.Message) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		chat := models.Chat{}
+		result := tx.Model(&chat).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "last_seq"}}}).
+			Where("name = ?", message.ChatName).
+			UpdateColumn("last_seq", gorm.Expr("last_seq + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("chat '%v' does not exist", message.ChatName)
+		}
+
+		message.Seq = chat.LastSeq
+		return tx.Create(message).Error
+	})
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "create_message",
+				"record_id": message.ChatName,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
+
+// Returns up to limit latest messages of the chat in chronological order.
+func (r *MessageRepository) GetLast(
+	ctx context.Context, chatName string, limit int,
+) ([]models.Message, error) {
+	messages := []models.Message{}
+	err := r.db.WithContext(ctx).
+		Where("chat_name = ?", chatName).
+		Order("seq desc").
+		Limit(limit).
+		Find(&messages).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "get_last_messages",
+				"record_id": chatName,
+			}).
+			Error()
+		return nil, err
+	}
+
+	reverse(messages)
+
+	return messages, nil
+}
+
+// Returns message of the chat with provided ID or nil if there is no such message.
+func (r *MessageRepository) Get(
+	ctx context.Context, chatName, id string,
+) (*models.Message, error) {
+	message := &models.Message{}
+	err := r.db.WithContext(ctx).
+		First(message, "chat_name = ? AND id = ?", chatName, id).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "get_message",
+				"record_id": id,
+			}).
+			Error()
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// Returns direct replies to the message in chronological order.
+func (r *MessageRepository) GetReplies(
+	ctx context.Context, chatName, parentID string,
+) ([]models.Message, error) {
+	messages := []models.Message{}
+	err := r.db.WithContext(ctx).
+		Where("chat_name = ? AND reply_to = ?", chatName, parentID).
+		Order("seq").
+		Find(&messages).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "get_message_replies",
+				"record_id": parentID,
+			}).
+			Error()
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// Replaces text of the message and marks it as edited.
+func (r *MessageRepository) Edit(ctx context.Context, id, text string) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.Message{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"text": text, "edited": true}).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "edit_message",
+				"record_id": id,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
+
+// Marks the message as deleted, erasing its text. Message record is kept
+// to preserve its place in chat history.
+func (r *MessageRepository) SetDeleted(ctx context.Context, id string) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.Message{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"text": "", "deleted": true}).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "set_message_deleted",
+				"record_id": id,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
+
+// Describes a page of chat messages. Zero values of cursors are ignored.
+type MessagePage struct {
+	BeforeSeq  uint64
+	AfterSeq   uint64
+	BeforeTime time.Time
+	AfterTime  time.Time
+	Limit      int
+}
+
+// Returns page of chat messages in chronological order and whether there are more
+// messages beyond the page. Messages are paginated backwards from the latest one
+// unless only "after" cursors are specified.
+func (r *MessageRepository) GetPage(
+	ctx context.Context, chatName string, page MessagePage,
+) (messages []models.Message, hasMore bool, err error) {
+	query := r.db.WithContext(ctx).Where("chat_name = ?", chatName)
+	if page.BeforeSeq != 0 {
+		query = query.Where("seq < ?", page.BeforeSeq)
+	}
+	if page.AfterSeq != 0 {
+		query = query.Where("seq > ?", page.AfterSeq)
+	}
+	if !page.BeforeTime.IsZero() {
+		query = query.Where("time < ?", page.BeforeTime)
+	}
+	if !page.AfterTime.IsZero() {
+		query = query.Where("time > ?", page.AfterTime)
+	}
+
+	forward := page.BeforeSeq == 0 && page.BeforeTime.IsZero() &&
+		(page.AfterSeq != 0 || !page.AfterTime.IsZero())
+	if forward {
+		query = query.Order("seq")
+	} else {
+		query = query.Order("seq desc")
+	}
+
+	messages = []models.Message{}
+	err = query.Limit(page.Limit + 1).Find(&messages).Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "get_messages_page",
+				"record_id": chatName,
+			}).
+			Error()
+		return nil, false, err
+	}
+
+	if len(messages) > page.Limit {
+		messages = messages[:page.Limit]
+		hasMore = true
+	}
+
+	if !forward {
+		reverse(messages)
+	}
+
+	return messages, hasMore, nil
+}
+
+func reverse(messages []models.Message) {
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+}
@@ -0,0 +1,228 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/shkotk/gochat/server/models"
+	"github.com/sirupsen/logrus"
+)
+
+func (s *DBTestSuite) TestMessage_Create_CancelledContext_ReturnsError() {
+	messageRepository := NewMessageRepository(logrus.StandardLogger(), s.testDB)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := messageRepository.Create(ctx, &models.Message{})
+
+	s.Equal(context.Canceled, err)
+}
+
+func (s *DBTestSuite) TestMessage_Create_ValidMessages_AddsRecordsWithIncreasingSeq() {
+	s.testDB.Create(&models.Chat{Name: "general"})
+	messageRepository := NewMessageRepository(logrus.StandardLogger(), s.testDB)
+	first := &models.Message{
+		ID:       "1",
+		ChatName: "general",
+		Producer: "stanley",
+		Text:     "hello",
+		Time:     time.Now(),
+	}
+	second := &models.Message{
+		ID:       "2",
+		ChatName: "general",
+		Producer: "kevin",
+		Text:     "hi",
+		Time:     time.Now(),
+	}
+
+	err := messageRepository.Create(context.Background(), first)
+	s.Nil(err)
+	err = messageRepository.Create(context.Background(), second)
+	s.Nil(err)
+
+	s.EqualValues(1, first.Seq)
+	s.EqualValues(2, second.Seq)
+
+	messages := []models.Message{}
+	s.testDB.Order("seq").Find(&messages)
+	s.Len(messages, 2)
+	s.Equal("general", messages[0].ChatName)
+	s.Equal("stanley", messages[0].Producer)
+	s.Equal("hello", messages[0].Text)
+
+	chat := models.Chat{}
+	s.testDB.First(&chat, "name = ?", "general")
+	s.EqualValues(2, chat.LastSeq)
+}
+
+func (s *DBTestSuite) TestMessage_Create_NotExistingChat_ReturnsError() {
+	messageRepository := NewMessageRepository(logrus.StandardLogger(), s.testDB)
+
+	err := messageRepository.Create(context.Background(), &models.Message{
+		ID:       "1",
+		ChatName: "general",
+		Producer: "stanley",
+		Text:     "hello",
+		Time:     time.Now(),
+	})
+
+	s.NotNil(err)
+}
+
+func (s *DBTestSuite) TestMessage_GetLast_PopulatedMessagesTable_ReturnsLatestInChronologicalOrder() {
+	now := time.Now()
+	s.testDB.Create([]models.Message{
+		{ID: "1", ChatName: "general", Seq: 1, Producer: "stanley", Text: "1", Time: now},
+		{ID: "2", ChatName: "general", Seq: 2, Producer: "kevin", Text: "2", Time: now},
+		{ID: "3", ChatName: "other", Seq: 1, Producer: "kevin", Text: "other", Time: now},
+		{ID: "4", ChatName: "general", Seq: 3, Producer: "stanley", Text: "3", Time: now},
+	})
+
+	messageRepository := NewMessageRepository(logrus.StandardLogger(), s.testDB)
+
+	messages, err := messageRepository.GetLast(context.Background(), "general", 2)
+
+	s.Nil(err)
+	s.Len(messages, 2)
+	s.Equal("2", messages[0].Text)
+	s.Equal("3", messages[1].Text)
+}
+
+func (s *DBTestSuite) TestMessage_GetPage_PopulatedMessagesTable_ReturnsExpectedPage() {
+	now := time.Now().Truncate(time.Second)
+	messages := []models.Message{
+		{ID: "1", ChatName: "general", Seq: 1, Producer: "stanley", Text: "1", Time: now},
+		{ID: "2", ChatName: "general", Seq: 2, Producer: "kevin", Text: "2", Time: now.Add(time.Second)},
+		{ID: "3", ChatName: "other", Seq: 1, Producer: "kevin", Text: "other", Time: now},
+		{ID: "4", ChatName: "general", Seq: 3, Producer: "stanley", Text: "3", Time: now.Add(2 * time.Second)},
+		{ID: "5", ChatName: "general", Seq: 4, Producer: "kevin", Text: "4", Time: now.Add(3 * time.Second)},
+	}
+	s.testDB.Create(&messages)
+
+	tests := []struct {
+		label           string
+		page            MessagePage
+		expectedTexts   []string
+		expectedHasMore bool
+	}{
+		{
+			label:           "latest",
+			page:            MessagePage{Limit: 2},
+			expectedTexts:   []string{"3", "4"},
+			expectedHasMore: true,
+		},
+		{
+			label:           "before seq",
+			page:            MessagePage{BeforeSeq: messages[3].Seq, Limit: 2},
+			expectedTexts:   []string{"1", "2"},
+			expectedHasMore: false,
+		},
+		{
+			label:           "after seq",
+			page:            MessagePage{AfterSeq: messages[0].Seq, Limit: 2},
+			expectedTexts:   []string{"2", "3"},
+			expectedHasMore: true,
+		},
+		{
+			label:           "after time",
+			page:            MessagePage{AfterTime: now.Add(time.Second), Limit: 5},
+			expectedTexts:   []string{"3", "4"},
+			expectedHasMore: false,
+		},
+		{
+			label:           "between seqs",
+			page:            MessagePage{AfterSeq: messages[0].Seq, BeforeSeq: messages[4].Seq, Limit: 5},
+			expectedTexts:   []string{"2", "3"},
+			expectedHasMore: false,
+		},
+	}
+
+	messageRepository := NewMessageRepository(logrus.StandardLogger(), s.testDB)
+
+	for _, test := range tests {
+		s.Run(test.label, func() {
+			actual, hasMore, err := messageRepository.GetPage(
+				context.Background(), "general", test.page)
+
+			s.Nil(err)
+			texts := make([]string, len(actual))
+			for i, message := range actual {
+				texts[i] = message.Text
+			}
+			s.Equal(test.expectedTexts, texts)
+			s.Equal(test.expectedHasMore, hasMore)
+		})
+	}
+}
+
+func (s *DBTestSuite) TestMessage_Get_PopulatedMessagesTable_ReturnsExpectedResult() {
+	s.testDB.Create([]models.Message{
+		{ID: "1", ChatName: "general", Seq: 1, Producer: "stanley", Text: "1", Time: time.Now()},
+		{ID: "2", ChatName: "other", Seq: 1, Producer: "kevin", Text: "other", Time: time.Now()},
+	})
+
+	messageRepository := NewMessageRepository(logrus.StandardLogger(), s.testDB)
+
+	message, err := messageRepository.Get(context.Background(), "general", "1")
+	s.Nil(err)
+	s.Equal("stanley", message.Producer)
+
+	message, err = messageRepository.Get(context.Background(), "general", "2")
+	s.Nil(err)
+	s.Nil(message)
+}
+
+func (s *DBTestSuite) TestMessage_Edit_ExistingMessage_UpdatesTextAndMarksEdited() {
+	s.testDB.Create(&models.Message{
+		ID: "1", ChatName: "general", Seq: 1, Producer: "stanley", Text: "1", Time: time.Now()})
+
+	messageRepository := NewMessageRepository(logrus.StandardLogger(), s.testDB)
+
+	err := messageRepository.Edit(context.Background(), "1", "edited")
+
+	s.Nil(err)
+
+	message := models.Message{}
+	s.testDB.First(&message, "id = ?", "1")
+	s.Equal("edited", message.Text)
+	s.True(message.Edited)
+}
+
+func (s *DBTestSuite) TestMessage_SetDeleted_ExistingMessage_ErasesTextAndMarksDeleted() {
+	s.testDB.Create(&models.Message{
+		ID: "1", ChatName: "general", Seq: 1, Producer: "stanley", Text: "1", Time: time.Now()})
+
+	messageRepository := NewMessageRepository(logrus.StandardLogger(), s.testDB)
+
+	err := messageRepository.SetDeleted(context.Background(), "1")
+
+	s.Nil(err)
+
+	message := models.Message{}
+	s.testDB.First(&message, "id = ?", "1")
+	s.Empty(message.Text)
+	s.True(message.Deleted)
+	s.Equal(uint64(1), message.Seq)
+}
+
+func (s *DBTestSuite) TestMessage_GetReplies_PopulatedMessagesTable_ReturnsRepliesInChronologicalOrder() {
+	now := time.Now()
+	s.testDB.Create([]models.Message{
+		{ID: "1", ChatName: "general", Seq: 1, Producer: "stanley", Text: "parent", Time: now},
+		{ID: "2", ChatName: "general", Seq: 2, Producer: "kevin", Text: "unrelated", Time: now},
+		{ID: "3", ChatName: "general", Seq: 3, Producer: "kevin", Text: "first", Time: now, ReplyTo: "1"},
+		{ID: "4", ChatName: "general", Seq: 4, Producer: "stanley", Text: "nested", Time: now, ReplyTo: "3"},
+		{ID: "5", ChatName: "general", Seq: 5, Producer: "stanley", Text: "second", Time: now, ReplyTo: "1"},
+	})
+
+	messageRepository := NewMessageRepository(logrus.StandardLogger(), s.testDB)
+
+	replies, err := messageRepository.GetReplies(context.Background(), "general", "1")
+
+	s.Nil(err)
+	s.Len(replies, 2)
+	s.Equal("first", replies[0].Text)
+	s.Equal("second", replies[1].Text)
+}
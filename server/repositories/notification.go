@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/shkotk/gochat/server/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type NotificationRepository struct {
+	logger *logrus.Logger
+	db     *gorm.DB
+}
+
+func NewNotificationRepository(logger *logrus.Logger, db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{logger, db}
+}
+
+func (r *NotificationRepository) Create(
+	ctx context.Context, notification models.Notification,
+) error {
+	err := r.db.WithContext(ctx).Create(&notification).Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "create_notification",
+				"record_id": notification.ID,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
+
+// Returns up to limit latest notifications of the user, newest first.
+func (r *NotificationRepository) GetLast(
+	ctx context.Context, username string, limit int,
+) ([]models.Notification, error) {
+	notifications := []models.Notification{}
+	err := r.db.WithContext(ctx).
+		Where("username = ?", username).
+		Order("time desc").
+		Limit(limit).
+		Find(&notifications).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "get_last_notifications",
+				"record_id": username,
+			}).
+			Error()
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (r *NotificationRepository) CountUnread(
+	ctx context.Context, username string,
+) (count int64, err error) {
+	err = r.db.WithContext(ctx).
+		Model(&models.Notification{}).
+		Where("username = ? AND NOT read", username).
+		Count(&count).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "count_unread_notifications",
+				"record_id": username,
+			}).
+			Error()
+	}
+
+	return
+}
+
+// Marks notifications of the user with provided IDs as read, or all of them
+// if no IDs are provided.
+func (r *NotificationRepository) MarkRead(
+	ctx context.Context, username string, ids []string,
+) error {
+	query := r.db.WithContext(ctx).
+		Model(&models.Notification{}).
+		Where("username = ?", username)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+
+	err := query.Update("read", true).Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "mark_notifications_read",
+				"record_id": username,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
+
+// Removes all notifications of the user.
+func (r *NotificationRepository) Clear(ctx context.Context, username string) error {
+	err := r.db.WithContext(ctx).
+		Delete(&models.Notification{}, "username = ?", username).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "clear_notifications",
+				"record_id": username,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/shkotk/gochat/server/models"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestNotification(id, username string, time time.Time, read bool) models.Notification {
+	return models.Notification{
+		ID:        id,
+		Username:  username,
+		Kind:      models.NotificationMention,
+		ChatName:  "general",
+		MessageID: "message" + id,
+		Producer:  "michael",
+		Text:      "@" + username,
+		Time:      time,
+		Read:      read,
+	}
+}
+
+func (s *DBTestSuite) TestNotification_GetLast_PopulatedNotificationsTable_ReturnsNewestFirst() {
+	now := time.Now()
+	s.testDB.Create([]models.Notification{
+		newTestNotification("1", "jim", now.Add(-2*time.Minute), true),
+		newTestNotification("2", "jim", now, false),
+		newTestNotification("3", "pam", now, false),
+		newTestNotification("4", "jim", now.Add(-time.Minute), false),
+	})
+
+	notificationRepository := NewNotificationRepository(logrus.StandardLogger(), s.testDB)
+
+	notifications, err := notificationRepository.GetLast(context.Background(), "jim", 2)
+
+	s.Nil(err)
+	s.Len(notifications, 2)
+	s.Equal("2", notifications[0].ID)
+	s.Equal("4", notifications[1].ID)
+
+	unread, err := notificationRepository.CountUnread(context.Background(), "jim")
+
+	s.Nil(err)
+	s.Equal(int64(2), unread)
+}
+
+func (s *DBTestSuite) TestNotification_MarkRead_SomeAndAll_UpdatesOnlyUserNotifications() {
+	now := time.Now()
+	s.testDB.Create([]models.Notification{
+		newTestNotification("1", "jim", now, false),
+		newTestNotification("2", "jim", now, false),
+		newTestNotification("3", "pam", now, false),
+	})
+
+	notificationRepository := NewNotificationRepository(logrus.StandardLogger(), s.testDB)
+
+	s.Nil(notificationRepository.MarkRead(context.Background(), "jim", []string{"1", "3"}))
+
+	unread := []models.Notification{}
+	s.testDB.Order("id").Find(&unread, "NOT read")
+	s.Len(unread, 2)
+	s.Equal("2", unread[0].ID)
+	s.Equal("3", unread[1].ID)
+
+	s.Nil(notificationRepository.MarkRead(context.Background(), "jim", nil))
+
+	unread = []models.Notification{}
+	s.testDB.Find(&unread, "NOT read")
+	s.Len(unread, 1)
+	s.Equal("3", unread[0].ID)
+}
+
+func (s *DBTestSuite) TestNotification_Clear_PopulatedNotificationsTable_RemovesUserNotifications() {
+	s.testDB.Create([]models.Notification{
+		newTestNotification("1", "jim", time.Now(), false),
+		newTestNotification("2", "pam", time.Now(), false),
+	})
+
+	notificationRepository := NewNotificationRepository(logrus.StandardLogger(), s.testDB)
+
+	err := notificationRepository.Clear(context.Background(), "jim")
+
+	s.Nil(err)
+
+	notifications := []models.Notification{}
+	s.testDB.Find(&notifications)
+	s.Len(notifications, 1)
+	s.Equal("pam", notifications[0].Username)
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/shkotk/gochat/server/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReactionRepository struct {
+	logger *logrus.Logger
+	db     *gorm.DB
+}
+
+func NewReactionRepository(logger *logrus.Logger, db *gorm.DB) *ReactionRepository {
+	return &ReactionRepository{logger, db}
+}
+
+// Stores reaction unless the same reaction was already added by the user.
+func (r *ReactionRepository) Add(ctx context.Context, reaction models.Reaction) error {
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&reaction).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "add_reaction",
+				"record_id": reaction.MessageID + "/" + reaction.Username,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
+
+func (r *ReactionRepository) Remove(
+	ctx context.Context, messageID, username, emoji string,
+) error {
+	err := r.db.WithContext(ctx).
+		Delete(&models.Reaction{},
+			"message_id = ? AND username = ? AND emoji = ?", messageID, username, emoji).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "remove_reaction",
+				"record_id": messageID + "/" + username,
+			}).
+			Error()
+		return err
+	}
+
+	return nil
+}
+
+// Returns reaction counts of provided messages grouped by message ID. Counts of
+// each message are ordered by the time emoji was first used to react to it.
+func (r *ReactionRepository) GetCounts(
+	ctx context.Context, messageIDs []string,
+) (map[string][]models.ReactionCount, error) {
+	counts := []models.ReactionCount{}
+	if len(messageIDs) > 0 {
+		err := r.db.WithContext(ctx).
+			Model(&models.Reaction{}).
+			Select("message_id, emoji, count(*) AS count").
+			Where("message_id IN ?", messageIDs).
+			Group("message_id, emoji").
+			Order("min(created_at), emoji").
+			Scan(&counts).
+			Error
+		if err != nil {
+			r.logger.WithError(err).
+				WithFields(logrus.Fields{
+					"action":    "get_reaction_counts",
+					"record_id": messageIDs,
+				}).
+				Error()
+			return nil, err
+		}
+	}
+
+	result := make(map[string][]models.ReactionCount)
+	for _, count := range counts {
+		result[count.MessageID] = append(result[count.MessageID], count)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/shkotk/gochat/server/models"
+	"github.com/sirupsen/logrus"
+)
+
+func (s *DBTestSuite) TestReaction_Add_SameReactionTwice_AddsSingleRecord() {
+	reactionRepository := NewReactionRepository(logrus.StandardLogger(), s.testDB)
+	reaction := models.Reaction{
+		MessageID: "1", Username: "jim", Emoji: "thumbsup", ChatName: "general"}
+
+	s.Nil(reactionRepository.Add(context.Background(), reaction))
+	s.Nil(reactionRepository.Add(context.Background(), reaction))
+
+	var count int64
+	s.testDB.Model(&models.Reaction{}).Count(&count)
+	s.Equal(int64(1), count)
+}
+
+func (s *DBTestSuite) TestReaction_Remove_ExistingReaction_RemovesOnlyIt() {
+	s.testDB.Create([]models.Reaction{
+		{MessageID: "1", Username: "jim", Emoji: "thumbsup", ChatName: "general"},
+		{MessageID: "1", Username: "jim", Emoji: "tada", ChatName: "general"},
+	})
+
+	reactionRepository := NewReactionRepository(logrus.StandardLogger(), s.testDB)
+
+	err := reactionRepository.Remove(context.Background(), "1", "jim", "thumbsup")
+
+	s.Nil(err)
+
+	reactions := []models.Reaction{}
+	s.testDB.Find(&reactions)
+	s.Len(reactions, 1)
+	s.Equal("tada", reactions[0].Emoji)
+}
+
+func (s *DBTestSuite) TestReaction_GetCounts_PopulatedReactionsTable_ReturnsCountsByMessage() {
+	now := time.Now()
+	s.testDB.Create([]models.Reaction{
+		{MessageID: "1", Username: "jim", Emoji: "tada", ChatName: "general", CreatedAt: now},
+		{MessageID: "1", Username: "pam", Emoji: "thumbsup", ChatName: "general", CreatedAt: now.Add(-time.Minute)},
+		{MessageID: "1", Username: "jim", Emoji: "thumbsup", ChatName: "general", CreatedAt: now},
+		{MessageID: "2", Username: "jim", Emoji: "tada", ChatName: "general", CreatedAt: now},
+		{MessageID: "3", Username: "jim", Emoji: "tada", ChatName: "general", CreatedAt: now},
+	})
+
+	reactionRepository := NewReactionRepository(logrus.StandardLogger(), s.testDB)
+
+	counts, err := reactionRepository.GetCounts(context.Background(), []string{"1", "2"})
+
+	s.Nil(err)
+	s.Len(counts, 2)
+	s.Equal([]models.ReactionCount{
+		{MessageID: "1", Emoji: "thumbsup", Count: 2},
+		{MessageID: "1", Emoji: "tada", Count: 1},
+	}, counts["1"])
+	s.Equal([]models.ReactionCount{{MessageID: "2", Emoji: "tada", Count: 1}}, counts["2"])
+}
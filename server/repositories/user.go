@@ -52,6 +52,53 @@ func (r *UserRepository) Create(ctx context.Context, user models.User) error {
 	return nil
 }
 
+// Returns provided usernames which belong to existing users who can be mentioned
+// in the chat, preserving their order. Only members of private and direct chats
+// can be mentioned in them, so that mention notifications don't leak their messages.
+func (r *UserRepository) FilterMentionable(
+	ctx context.Context, chatName string, usernames []string,
+) ([]string, error) {
+	existing := []string{}
+	if len(usernames) == 0 {
+		return existing, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("username IN ?", usernames).
+		Where(
+			r.db.Where("NOT EXISTS (?)", r.db.Model(&models.Chat{}).
+				Select("1").
+				Where("name = ? AND private", chatName)).
+				Or("EXISTS (?)", r.db.Model(&models.ChatMember{}).
+					Select("1").
+					Where("chat_members.chat_name = ? AND chat_members.username = users.username", chatName)),
+		).
+		Pluck("username", &existing).
+		Error
+	if err != nil {
+		r.logger.WithError(err).
+			WithFields(logrus.Fields{
+				"action":    "filter_mentionable_users",
+				"record_id": chatName,
+			}).
+			Error()
+		return nil, err
+	}
+
+	result := []string{}
+	for _, username := range usernames {
+		for _, existingUsername := range existing {
+			if username == existingUsername {
+				result = append(result, username)
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
 // Returns user corresponding to provided username or nil if it does not exist.
 func (r *UserRepository) Get(ctx context.Context, username string) (*models.User, error) {
 	user := &models.User{}
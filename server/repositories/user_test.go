@@ -169,3 +169,56 @@ func (s *DBTestSuite) TestUser_Get_PopulatedUsersTable_ReturnsExpectedResult() {
 		})
 	}
 }
+
+func (s *DBTestSuite) TestUser_FilterMentionable_PublicChat_ReturnsExistingInOrder() {
+	s.testDB.Create([]models.User{
+		{Username: "stanley", PasswordHash: "somehash"},
+		{Username: "kevin", PasswordHash: "otherhash"},
+	})
+	s.testDB.Create(&models.Chat{Name: "general"})
+
+	userRepository := NewUserRepository(logrus.StandardLogger(), s.testDB)
+
+	existing, err := userRepository.FilterMentionable(
+		context.Background(), "general", []string{"kevin", "michael", "stanley"})
+
+	s.Nil(err)
+	s.Equal([]string{"kevin", "stanley"}, existing)
+}
+
+func (s *DBTestSuite) TestUser_FilterMentionable_PrivateChats_ReturnsMembersOnly() {
+	s.testDB.Create([]models.User{
+		{Username: "jim", PasswordHash: "somehash"},
+		{Username: "pam", PasswordHash: "otherhash"},
+		{Username: "dwight", PasswordHash: "thirdhash"},
+	})
+	s.testDB.Create([]models.Chat{
+		{
+			Name:    "secret",
+			Private: true,
+			Members: []models.ChatMember{
+				{Username: "jim", Role: models.RoleOwner},
+				{Username: "pam", Role: models.RoleMember},
+			},
+		},
+		{
+			Name:    "dm:jim:pam",
+			Private: true,
+			Direct:  true,
+			Members: []models.ChatMember{
+				{Username: "jim", Role: models.RoleMember},
+				{Username: "pam", Role: models.RoleMember},
+			},
+		},
+	})
+
+	userRepository := NewUserRepository(logrus.StandardLogger(), s.testDB)
+
+	for _, chatName := range []string{"secret", "dm:jim:pam"} {
+		mentionable, err := userRepository.FilterMentionable(
+			context.Background(), chatName, []string{"dwight", "pam"})
+
+		s.Nil(err)
+		s.Equal([]string{"pam"}, mentionable, "non-member was mentioned in '%s'", chatName)
+	}
+}
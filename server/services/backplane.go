@@ -0,0 +1,115 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Number of payloads buffered for a backplane subscriber before new ones are dropped.
+const backplaneSubscriberBuffer = 256
+
+// Chat message exchanged between server instances through the backplane.
+type envelope struct {
+	// ID of the server instance which published the message.
+	Origin string `json:"origin"`
+
+	// Type and JSON of serialized event to deliver to chat members.
+	EventType string          `json:"eventType,omitempty"`
+	Event     json.RawMessage `json:"event,omitempty"`
+
+	// Set if chat was stopped, e.g. deleted or archived.
+	Stop *stopChatRequest `json:"stop,omitempty"`
+
+	// Set when origin starts running the chat to request members connected
+	// to other instances.
+	RequestMembers bool `json:"requestMembers,omitempty"`
+
+	// Members connected to origin, sent in response to members request.
+	Members []string `json:"members,omitempty"`
+
+	// Set when origin stops running the chat, so its members have left.
+	Left bool `json:"left,omitempty"`
+}
+
+// Creates envelope carrying event serialized with events.Serialize.
+// Event JSON is embedded as is, so it isn't escaped again.
+func eventEnvelope(serialized []byte) envelope {
+	eventType, event, _ := bytes.Cut(serialized, []byte("|"))
+	return envelope{EventType: string(eventType), Event: event}
+}
+
+// Gets carried event in events.Serialize format, ready to be parsed with events.Parse.
+func (e envelope) serializedEvent() []byte {
+	return append([]byte(e.EventType+"|"), e.Event...)
+}
+
+func (e envelope) encode() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func decodeEnvelope(payload []byte) (envelope, error) {
+	var e envelope
+	err := json.Unmarshal(payload, &e)
+	return e, err
+}
+
+// Subscribers of backplane topics within a server instance.
+type backplaneSubscribers struct {
+	subscribers map[string]map[chan []byte]struct{}
+	lock        sync.RWMutex
+
+	logger *logrus.Logger
+}
+
+func newBackplaneSubscribers(logger *logrus.Logger) *backplaneSubscribers {
+	return &backplaneSubscribers{
+		subscribers: make(map[string]map[chan []byte]struct{}),
+		logger:      logger,
+	}
+}
+
+func (s *backplaneSubscribers) subscribe(ctx context.Context, topic string) <-chan []byte {
+	subscriber := make(chan []byte, backplaneSubscriberBuffer)
+
+	s.lock.Lock()
+	topicSubscribers, ok := s.subscribers[topic]
+	if !ok {
+		topicSubscribers = make(map[chan []byte]struct{})
+		s.subscribers[topic] = topicSubscribers
+	}
+	topicSubscribers[subscriber] = struct{}{}
+	s.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		delete(topicSubscribers, subscriber)
+		if len(topicSubscribers) == 0 {
+			delete(s.subscribers, topic)
+		}
+		close(subscriber)
+	}()
+
+	return subscriber
+}
+
+// Passes payload to all subscribers of the topic without blocking.
+func (s *backplaneSubscribers) dispatch(topic string, payload []byte) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for subscriber := range s.subscribers[topic] {
+		select {
+		case subscriber <- payload:
+		default:
+			s.logger.Warnf("backplane: subscriber of '%s' is full, dropping payload", topic)
+		}
+	}
+}
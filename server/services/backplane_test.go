@@ -0,0 +1,34 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventEnvelope_EncodeDecode_RoundTripsEvent(t *testing.T) {
+	event := &events.NewMessage{
+		Chat:     "general",
+		Producer: "jim",
+		Text:     `"quoted" \\ text`,
+		Time:     time.Now().UTC().Round(time.Millisecond),
+	}
+	serialized, err := events.Serialize(event)
+	require.Nil(t, err)
+	envelope := eventEnvelope(serialized)
+	envelope.Origin = "first"
+
+	payload, err := envelope.encode()
+	require.Nil(t, err)
+	decoded, err := decodeEnvelope(payload)
+	require.Nil(t, err)
+	parsed, err := events.Parse(decoded.serializedEvent())
+	require.Nil(t, err)
+
+	assert.Contains(t, string(payload), `"event":{`, "event JSON was escaped")
+	assert.Equal(t, "first", decoded.Origin)
+	assert.Equal(t, event, parsed)
+}
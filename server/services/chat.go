@@ -1,137 +1,863 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/config"
 	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/shkotk/gochat/server/models"
+	"github.com/shkotk/gochat/server/repositories"
+	"github.com/shkotk/gochat/server/websocket"
 	"github.com/sirupsen/logrus"
 )
 
+// Returned when client is added to a chat which is already stopped.
+var errChatStopped = errors.New("chat is stopped")
+
+const (
+	// Minimal interval between relayed typing notifications of the same user.
+	typingInterval = 2 * time.Second
+
+	// Period user is considered typing for after typing notification.
+	typingTimeout = 5 * time.Second
+)
+
 type Chat struct {
 	Name string
 
+	// ID of the server instance running the chat.
+	instanceID string
+
+	// Clients connected to this server instance.
 	members map[string]interfaces.Client
+	// Users connected to any server instance.
+	presence *presence
+	// Time of the last relayed typing notification by user.
+	lastTyping map[string]time.Time
+	// Time until which user can't post to chat or invoke commands. Mutes are kept
+	// in memory only and are lost once chat is unloaded.
+	muted map[string]time.Time
+	// Guards muted, which is checked by goroutines pumping members' events.
+	mutedLock sync.Mutex
+
+	events          chan any
+	joinRequests    chan joinChatRequest
+	leaveRequests   chan leaveChatRequest
+	membersRequests chan chan []string
+	stopRequests    chan stopChatRequest
+	done            chan struct{}
 
-	events        chan any
-	joinRequests  chan joinChatRequest
-	leaveRequests chan leaveChatRequest
+	// Fires when chat has no members for configured idle timeout.
+	idleTimer *time.Timer
+	idle      <-chan time.Time
 
-	eventsPreProcessor interfaces.EventPreProcessor
-	logger             *logrus.Logger
+	goroutines     sync.WaitGroup
+	goroutineCount atomic.Int64
+
+	cfg                    config.ChatConfig
+	backplane              interfaces.Backplane
+	notifier               interfaces.Notifier
+	eventsPreProcessor     interfaces.EventPreProcessor
+	messageRepository      *repositories.MessageRepository
+	reactionRepository     *repositories.ReactionRepository
+	notificationRepository *repositories.NotificationRepository
+	logger                 *logrus.Logger
 }
 
 func NewChat(
 	chatName string,
+	instanceID string,
+	cfg config.ChatConfig,
+	backplane interfaces.Backplane,
+	notifier interfaces.Notifier,
 	eventsPreProcessor interfaces.EventPreProcessor,
+	messageRepository *repositories.MessageRepository,
+	reactionRepository *repositories.ReactionRepository,
+	notificationRepository *repositories.NotificationRepository,
 	logger *logrus.Logger,
 ) *Chat {
 	return &Chat{
-		Name:               chatName,
-		members:            make(map[string]interfaces.Client),
-		events:             make(chan any),
-		joinRequests:       make(chan joinChatRequest),
-		leaveRequests:      make(chan leaveChatRequest),
-		eventsPreProcessor: eventsPreProcessor,
-		logger:             logger,
+		Name:                   chatName,
+		instanceID:             instanceID,
+		members:                make(map[string]interfaces.Client),
+		presence:               newPresence(),
+		lastTyping:             make(map[string]time.Time),
+		muted:                  make(map[string]time.Time),
+		events:                 make(chan any),
+		joinRequests:           make(chan joinChatRequest),
+		leaveRequests:          make(chan leaveChatRequest),
+		membersRequests:        make(chan chan []string),
+		stopRequests:           make(chan stopChatRequest),
+		done:                   make(chan struct{}),
+		cfg:                    cfg,
+		backplane:              backplane,
+		notifier:               notifier,
+		eventsPreProcessor:     eventsPreProcessor,
+		messageRepository:      messageRepository,
+		reactionRepository:     reactionRepository,
+		notificationRepository: notificationRepository,
+		logger:                 logger,
 	}
 }
 
-func (c *Chat) AddClient(client interfaces.Client) error {
+// Adds client to chat. Messages with sequence numbers greater than lastSeq are
+// replayed to the client, or the latest ones if lastSeq is zero.
+// Returns errChatStopped if chat is stopped.
+func (c *Chat) AddClient(client interfaces.Client, lastSeq uint64) error {
 	err := make(chan error)
-	c.joinRequests <- joinChatRequest{
-		Client: client,
-		Err:    err,
+	select {
+	case c.joinRequests <- joinChatRequest{Client: client, LastSeq: lastSeq, Err: err}:
+	case <-c.done:
+		return errChatStopped
 	}
 
 	return <-err
 }
 
-// Loop processing join and leave requests, events from clients in chat.
-func (c *Chat) Run() {
+// Gets sorted usernames of users in chat. Returns errChatStopped if chat is stopped.
+func (c *Chat) Members() ([]string, error) {
+	members := make(chan []string)
+	select {
+	case c.membersRequests <- members:
+	case <-c.done:
+		return nil, errChatStopped
+	}
+
+	return <-members, nil
+}
+
+// Stops chat loop, notifying all members with provided reason and disconnecting them
+// with provided close code. Blocks until chat is stopped and all its goroutines are finished.
+func (c *Chat) Stop(reason string, closeCode int) {
+	select {
+	case c.stopRequests <- stopChatRequest{Reason: reason, CloseCode: closeCode}:
+	case <-c.done:
+	}
+
+	c.Wait()
+}
+
+// Blocks until chat loop is stopped and all goroutines started by chat are finished.
+func (c *Chat) Wait() {
+	<-c.done
+	c.goroutines.Wait()
+}
+
+// Gets channel which is closed when chat loop is stopped.
+func (c *Chat) Done() <-chan struct{} {
+	return c.done
+}
+
+// Gets number of goroutines currently run by chat, including chat loop.
+func (c *Chat) Goroutines() int64 {
+	return c.goroutineCount.Load()
+}
+
+// Loop processing join and leave requests, events from clients in chat and events
+// published to the chat by other server instances. Returns when chat is stopped,
+// context is cancelled or chat stays empty for configured idle timeout.
+func (c *Chat) Run(ctx context.Context) {
+	c.goroutineCount.Add(1)
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		c.stopIdleTimer()
+		close(c.done)
+		c.goroutineCount.Add(-1)
+	}()
+
+	remote := c.backplane.Subscribe(ctx, c.Name)
+	c.publishEnvelope(envelope{RequestMembers: true})
+	c.resetIdleTimer()
+
 	for {
 		select {
 		case request := <-c.joinRequests:
 			c.processJoinRequest(request)
 		case request := <-c.leaveRequests:
 			c.processLeaveRequest(request)
+		case members := <-c.membersRequests:
+			members <- c.presence.usernames()
 		case event := <-c.events:
-			c.broadcast(event)
+			c.processEvent(event)
+		case payload := <-remote:
+			if !c.processRemote(payload) {
+				return
+			}
+		case request := <-c.stopRequests:
+			c.processStopRequest(request)
+			return
+		case <-ctx.Done():
+			c.processStopRequest(stopChatRequest{
+				Reason:    "chat was stopped",
+				CloseCode: interfaces.CloseGoingAway,
+			})
+			return
+		case <-c.idle:
+			c.logger.Debugf("chat: stopping idle chat '%s'", c.Name)
+			return
 		}
 	}
 }
 
+// Adds client to chat. A user can be in chat with a single client only, so fresh join
+// of a user who is already in chat is rejected. Resuming client replaces the previous
+// one instead, as it's most likely its own connection lingering after reconnect.
 func (c *Chat) processJoinRequest(request joinChatRequest) {
 	client := request.Client
-	if _, ok := c.members[client.ID()]; ok {
-		request.Err <- fmt.Errorf(
-			"client '%s' is already in chat '%s'", client.ID(), c.Name)
-		return
+	if previous, ok := c.members[client.ID()]; ok {
+		if request.LastSeq == 0 {
+			request.Err <- fmt.Errorf(
+				"client '%s' is already in chat '%s'", client.ID(), c.Name)
+			return
+		}
+
+		delete(c.members, client.ID())
+		previous.Close(interfaces.CloseNormal, "replaced by resumed connection")
 	}
 
 	request.Err <- nil
-	c.broadcast(&events.SystemMessage{
-		Text: fmt.Sprintf("%s joined chat", client.ID()),
-		Time: time.Now(),
-	})
+	c.stopIdleTimer()
+
+	event := &events.MemberJoined{Chat: c.Name, Username: client.ID(), Time: time.Now()}
+	if c.presence.add(client.ID(), c.instanceID) {
+		c.deliver(event)
+	}
+	c.publish(event)
+
 	c.members[client.ID()] = client
+	client.Send(&events.Members{
+		Chat:      c.Name,
+		Usernames: c.presence.usernames(),
+		Time:      time.Now(),
+	})
+
+	c.replayHistory(client, request.LastSeq)
 
-	go c.pumpMessages(client)
+	c.spawn(func() { c.pumpMessages(client) })
 }
 
 func (c *Chat) processLeaveRequest(request leaveChatRequest) {
-	if _, ok := c.members[request.ClientID]; !ok {
-		c.logger.Warnf(
-			"chat: can't process leave request, user '%s' is not in chat", request.ClientID)
+	if c.members[request.ClientID] != request.Client {
+		c.logger.Debugf(
+			"chat: ignoring leave request, client of '%s' is not in chat", request.ClientID)
 		return
 	}
 
 	delete(c.members, request.ClientID)
-	c.broadcast(&events.SystemMessage{
-		Text: fmt.Sprintf("%s left chat", request.ClientID),
+	delete(c.lastTyping, request.ClientID)
+	if len(c.members) == 0 {
+		c.resetIdleTimer()
+	}
+
+	event := &events.MemberLeft{Chat: c.Name, Username: request.ClientID, Time: time.Now()}
+	if c.presence.remove(request.ClientID, c.instanceID) {
+		c.deliver(event)
+	}
+	c.publish(event)
+}
+
+// Notifies members that chat is stopped and disconnects them.
+func (c *Chat) processStopRequest(request stopChatRequest) {
+	event := &events.SystemMessage{
+		Chat: c.Name,
+		Text: request.Reason,
 		Time: time.Now(),
-	})
+	}
+	c.deliver(event)
+	for _, client := range c.members {
+		client.Close(request.CloseCode, request.Reason)
+	}
+
+	if len(c.members) > 0 {
+		c.publishEnvelope(envelope{Left: true})
+	}
+	c.members = make(map[string]interfaces.Client)
+}
+
+// Stores and broadcasts event from a member. Typing notifications sent more often
+// than typingInterval are dropped, message changes are broadcast once applied.
+// Users who missed a message mentioning them or a direct message are notified separately.
+func (c *Chat) processEvent(event any) {
+	switch event := event.(type) {
+	case chatAction:
+		event(c)
+		return
+	case *events.MemberKicked:
+		c.broadcast(event)
+		c.kick(event.Username, event.KickedBy)
+		return
+	case *events.MemberMuted:
+		c.mute(event.Username, event.Until)
+	case *events.EditMessage:
+		c.editMessage(event)
+		return
+	case *events.DeleteMessage:
+		c.deleteMessage(event)
+		return
+	case *events.AddReaction:
+		c.addReaction(event)
+		return
+	case *events.RemoveReaction:
+		c.removeReaction(event)
+		return
+	case *events.Typing:
+		if event.Time.Sub(c.lastTyping[event.Producer]) < typingInterval {
+			return
+		}
+		c.lastTyping[event.Producer] = event.Time
+		event.Until = event.Time.Add(typingTimeout)
+	case *events.NewMessage:
+		delete(c.lastTyping, event.Producer) // user might start typing the next one at once
+	}
+
+	if !c.store(event) {
+		return
+	}
+	c.broadcast(event)
+
+	if message, ok := event.(*events.NewMessage); ok {
+		c.notifyMentioned(message)
+		c.notifyDirect(message)
+	}
 }
 
 // Reads incoming events from client and pumps them to chat events channel.
+// Events of muted users are rejected before pre-processing, so they can't
+// invoke commands either.
 func (c *Chat) pumpMessages(client interfaces.Client) {
 	for {
 		select {
 		case event := <-client.In():
-			err := c.eventsPreProcessor.PreProcess(event, client)
+			if event, ok := event.(events.Routed); ok {
+				event.SetChat(c.Name)
+			}
+
+			if until, muted := c.mutedUntil(client.ID()); muted {
+				client.Send(&events.Error{
+					Chat: c.Name,
+					Text: fmt.Sprintf("you are muted for %v", time.Until(until).Round(time.Second)),
+					Time: time.Now(),
+				})
+				continue
+			}
+
+			processed, err := c.eventsPreProcessor.PreProcess(event, client)
 			if err != nil {
 				c.logger.WithError(err).Warnf(
 					"chat: error pre-processing event from '%s'", client.ID())
+				if errors.Is(err, errFlooding) {
+					client.Send(&events.Error{
+						Chat: c.Name,
+						Text: "disconnected from chat for flooding",
+						Time: time.Now(),
+					})
+					client.Close(interfaces.CloseFlooding, "too many events")
+					continue
+				}
+				if rejected := (eventRejectedError{}); errors.As(err, &rejected) {
+					client.Send(&events.Error{Chat: c.Name, Text: rejected.text, Time: time.Now()})
+				}
 				continue
 			}
-			c.events <- event
+
+			for _, event := range processed {
+				select {
+				case c.events <- event:
+				case <-c.done:
+					return
+				}
+			}
 
 		case <-client.Done():
-			c.leaveRequests <- leaveChatRequest{client.ID()}
+			select {
+			case c.leaveRequests <- leaveChatRequest{client.ID(), client}:
+			case <-c.done:
+			}
+			return
+
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Processes message published to the chat by any server instance, ignoring ones
+// published by this instance. Returns false if chat was stopped.
+func (c *Chat) processRemote(payload []byte) bool {
+	received, err := decodeEnvelope(payload)
+	if err != nil {
+		c.logger.WithError(err).Errorf(
+			"chat: failed to decode backplane message in chat '%s'", c.Name)
+		return true
+	}
+	if received.Origin == c.instanceID {
+		return true
+	}
+
+	switch {
+	case received.Stop != nil:
+		c.processStopRequest(*received.Stop)
+		return false
+	case received.RequestMembers:
+		if len(c.members) > 0 {
+			c.publishEnvelope(envelope{Members: c.localMembers()})
+		}
+	case received.Members != nil:
+		for _, username := range received.Members {
+			if c.presence.add(username, received.Origin) {
+				c.deliver(&events.MemberJoined{Chat: c.Name, Username: username, Time: time.Now()})
+			}
+		}
+	case received.Left:
+		for _, username := range c.presence.removeInstance(received.Origin) {
+			c.deliver(&events.MemberLeft{Chat: c.Name, Username: username, Time: time.Now()})
+		}
+	default:
+		c.processRemoteEvent(received)
+	}
+
+	return true
+}
+
+func (c *Chat) processRemoteEvent(received envelope) {
+	event, err := events.Parse(received.serializedEvent())
+	if err != nil {
+		c.logger.WithError(err).Errorf(
+			"chat: failed to parse event from instance '%s' in chat '%s'", received.Origin, c.Name)
+		return
+	}
+
+	switch event := event.(type) {
+	case *events.MemberJoined:
+		if !c.presence.add(event.Username, received.Origin) {
+			return
+		}
+	case *events.MemberKicked:
+		c.deliver(event)
+		c.kick(event.Username, event.KickedBy)
+		return
+	case *events.MemberMuted:
+		c.mute(event.Username, event.Until)
+	case *events.MemberLeft:
+		if !c.presence.remove(event.Username, received.Origin) {
 			return
 		}
 	}
+
+	c.deliver(event)
 }
 
+// Gets usernames of members connected to this server instance.
+func (c *Chat) localMembers() []string {
+	members := make([]string, 0, len(c.members))
+	for username := range c.members {
+		members = append(members, username)
+	}
+
+	return members
+}
+
+// Persists event if it's a part of chat history, assigning message ID and sequence
+// number. Returns false if event failed to be stored, notifying its producer.
+func (c *Chat) store(event any) bool {
+	message, ok := event.(*events.NewMessage)
+	if !ok {
+		return true
+	}
+
+	stored := &models.Message{
+		ID:       uuid.NewString(),
+		ChatName: c.Name,
+		Producer: message.Producer,
+		Text:     message.Text,
+		Time:     message.Time,
+		ReplyTo:  message.ReplyTo,
+		Mentions: message.Mentions,
+		Action:   message.Action,
+	}
+	err := c.messageRepository.Create(context.Background(), stored)
+	if err != nil {
+		c.logger.WithError(err).Errorf(
+			"chat: failed to store message from '%s' in chat '%s'", message.Producer, c.Name)
+		c.notifyError(message.Producer, "failed to send message")
+		return false
+	}
+
+	message.ID = stored.ID
+	message.Seq = stored.Seq
+
+	return true
+}
+
+// Records notification for users mentioned in the message who aren't in chat
+// and notifies them, wherever they are connected.
+func (c *Chat) notifyMentioned(message *events.NewMessage) {
+	for _, username := range message.Mentions {
+		if username == message.Producer || c.presence.has(username) {
+			continue
+		}
+
+		c.recordNotification(username, models.NotificationMention, message)
+		err := c.notifier.Notify(context.Background(), username, &events.Mentioned{
+			MessageID: message.ID,
+			Chat:      c.Name,
+			Producer:  message.Producer,
+			Time:      message.Time,
+			Text:      message.Text,
+		})
+		if err != nil {
+			c.logger.WithError(err).Errorf(
+				"chat: failed to notify '%s' about mention in chat '%s'", username, c.Name)
+		}
+	}
+}
+
+// Records notification for direct message recipient if they aren't in chat
+// and weren't notified about mention.
+func (c *Chat) notifyDirect(message *events.NewMessage) {
+	if !strings.HasPrefix(c.Name, directChatPrefix) {
+		return
+	}
+
+	peer := DirectChatPeer(c.Name, message.Producer)
+	if peer == message.Producer || c.presence.has(peer) {
+		return
+	}
+	for _, username := range message.Mentions {
+		if username == peer {
+			return
+		}
+	}
+
+	c.recordNotification(peer, models.NotificationDirect, message)
+}
+
+// Stores notification about the message to user's inbox.
+func (c *Chat) recordNotification(
+	username string, kind models.NotificationKind, message *events.NewMessage,
+) {
+	err := c.notificationRepository.Create(context.Background(), models.Notification{
+		ID:        uuid.NewString(),
+		Username:  username,
+		Kind:      kind,
+		ChatName:  c.Name,
+		MessageID: message.ID,
+		Producer:  message.Producer,
+		Text:      message.Text,
+		Time:      message.Time,
+	})
+	if err != nil {
+		c.logger.WithError(err).Errorf(
+			"chat: failed to record notification for '%s' in chat '%s'", username, c.Name)
+	}
+}
+
+// Persists new text of the message and broadcasts the change.
+func (c *Chat) editMessage(event *events.EditMessage) {
+	err := c.messageRepository.Edit(context.Background(), event.ID, event.Text)
+	if err != nil {
+		c.logger.WithError(err).Errorf(
+			"chat: failed to edit message '%s' in chat '%s'", event.ID, c.Name)
+		c.notifyError(event.Producer, "failed to edit message")
+		return
+	}
+
+	c.broadcast(&events.MessageEdited{
+		ID:       event.ID,
+		Chat:     c.Name,
+		EditedBy: event.Producer,
+		Time:     event.Time,
+		Text:     event.Text,
+	})
+}
+
+// Marks the message as deleted and broadcasts the change.
+func (c *Chat) deleteMessage(event *events.DeleteMessage) {
+	err := c.messageRepository.SetDeleted(context.Background(), event.ID)
+	if err != nil {
+		c.logger.WithError(err).Errorf(
+			"chat: failed to delete message '%s' in chat '%s'", event.ID, c.Name)
+		c.notifyError(event.Producer, "failed to delete message")
+		return
+	}
+
+	c.broadcast(&events.MessageDeleted{
+		ID:        event.ID,
+		Chat:      c.Name,
+		DeletedBy: event.Producer,
+		Time:      event.Time,
+	})
+}
+
+func (c *Chat) addReaction(event *events.AddReaction) {
+	err := c.reactionRepository.Add(context.Background(), models.Reaction{
+		MessageID: event.MessageID,
+		Username:  event.Producer,
+		Emoji:     event.Emoji,
+		ChatName:  c.Name,
+		CreatedAt: event.Time,
+	})
+	if err != nil {
+		c.logger.WithError(err).Errorf(
+			"chat: failed to add reaction to message '%s' in chat '%s'", event.MessageID, c.Name)
+		c.notifyError(event.Producer, "failed to add reaction")
+		return
+	}
+
+	c.broadcastReactions(event.MessageID)
+}
+
+func (c *Chat) removeReaction(event *events.RemoveReaction) {
+	err := c.reactionRepository.Remove(
+		context.Background(), event.MessageID, event.Producer, event.Emoji)
+	if err != nil {
+		c.logger.WithError(err).Errorf(
+			"chat: failed to remove reaction from message '%s' in chat '%s'", event.MessageID, c.Name)
+		c.notifyError(event.Producer, "failed to remove reaction")
+		return
+	}
+
+	c.broadcastReactions(event.MessageID)
+}
+
+// Broadcasts current reaction counts of the message.
+func (c *Chat) broadcastReactions(messageID string) {
+	counts, err := c.reactionRepository.GetCounts(context.Background(), []string{messageID})
+	if err != nil {
+		c.logger.WithError(err).Errorf(
+			"chat: failed to load reactions to message '%s' in chat '%s'", messageID, c.Name)
+		return
+	}
+
+	c.broadcast(&events.ReactionsUpdated{
+		MessageID: messageID,
+		Chat:      c.Name,
+		Time:      time.Now(),
+		Reactions: toReactionCounts(counts[messageID]),
+	})
+}
+
+// Disconnects user's client connected to this server instance, if any.
+func (c *Chat) kick(username, kickedBy string) {
+	if client, ok := c.members[username]; ok {
+		client.Close(interfaces.CloseKicked, fmt.Sprintf("kicked by %s", kickedBy))
+	}
+}
+
+func (c *Chat) mute(username string, until time.Time) {
+	c.mutedLock.Lock()
+	defer c.mutedLock.Unlock()
+
+	c.muted[username] = until
+}
+
+// Gets time until which user is muted, forgetting expired mute.
+// Returns false if user isn't muted.
+func (c *Chat) mutedUntil(username string) (time.Time, bool) {
+	c.mutedLock.Lock()
+	defer c.mutedLock.Unlock()
+
+	until, ok := c.muted[username]
+	if ok && !time.Now().Before(until) {
+		delete(c.muted, username)
+		return time.Time{}, false
+	}
+
+	return until, ok
+}
+
+// Sends error to the member connected to this server instance, if any.
+func (c *Chat) notifyError(username, text string) {
+	if client, ok := c.members[username]; ok {
+		client.Send(&events.Error{Chat: c.Name, Text: text, Time: time.Now()})
+	}
+}
+
+// Sends stored messages which follow lastSeq to the client, or the latest ones
+// if lastSeq is zero. If client missed more messages than can be replayed, the
+// earliest ones are sent followed by events.ReplayTruncated. Since replay happens
+// within chat loop, live events are sent to the client only after replayed messages.
+func (c *Chat) replayHistory(client interfaces.Client, lastSeq uint64) {
+	var messages []models.Message
+	var hasMore bool
+	var err error
+	if lastSeq == 0 {
+		if c.cfg.HistoryReplaySize <= 0 {
+			return
+		}
+		messages, err = c.messageRepository.GetLast(
+			context.Background(), c.Name, c.cfg.HistoryReplaySize)
+	} else {
+		if c.cfg.ResumeReplayLimit <= 0 {
+			return
+		}
+		messages, hasMore, err = c.messageRepository.GetPage(
+			context.Background(), c.Name, repositories.MessagePage{
+				AfterSeq: lastSeq,
+				Limit:    c.cfg.ResumeReplayLimit,
+			})
+	}
+	if err != nil {
+		c.logger.WithError(err).Errorf(
+			"chat: failed to load history of chat '%s' for '%s'", c.Name, client.ID())
+		return
+	}
+
+	messageIDs := make([]string, len(messages))
+	for i, message := range messages {
+		messageIDs[i] = message.ID
+	}
+	reactions, err := c.reactionRepository.GetCounts(context.Background(), messageIDs)
+	if err != nil {
+		c.logger.WithError(err).Errorf(
+			"chat: failed to load reactions in chat '%s' for '%s'", c.Name, client.ID())
+	}
+
+	for _, message := range messages {
+		client.Send(&events.NewMessage{
+			ID:       message.ID,
+			Seq:      message.Seq,
+			Chat:     c.Name,
+			Producer: message.Producer,
+			Time:     message.Time,
+			Text:     message.Text,
+			ReplyTo:  message.ReplyTo,
+			Mentions: message.Mentions,
+			Action:   message.Action,
+			Edited:   message.Edited,
+			Deleted:  message.Deleted,
+
+			Reactions: toReactionCounts(reactions[message.ID]),
+		})
+	}
+
+	if hasMore {
+		client.Send(&events.ReplayTruncated{
+			Chat: c.Name,
+			Seq:  messages[len(messages)-1].Seq,
+			Time: time.Now(),
+		})
+	}
+}
+
+func toReactionCounts(counts []models.ReactionCount) []events.ReactionCount {
+	result := make([]events.ReactionCount, len(counts))
+	for i, count := range counts {
+		result[i] = events.ReactionCount{Emoji: count.Emoji, Count: count.Count}
+	}
+
+	return result
+}
+
+// Sends event to members connected to all server instances.
 func (c *Chat) broadcast(event any) {
-	for _, client := range c.members {
-		go send(event, client)
+	c.deliver(event)
+	c.publish(event)
+}
+
+// Sends event to members connected to this server instance, serializing it only once.
+// Typing notifications aren't sent back to their producer.
+func (c *Chat) deliver(event any) {
+	if len(c.members) == 0 {
+		return
+	}
+
+	frame, err := websocket.NewFrame(event)
+	if err != nil {
+		c.logger.WithError(err).Errorf(
+			"chat: failed to serialize event of type %T in chat '%s'", event, c.Name)
+		return
+	}
+
+	skip := ""
+	if typing, ok := event.(*events.Typing); ok {
+		skip = typing.Producer
+	}
+
+	for id, client := range c.members {
+		if id != skip {
+			client.Send(frame)
+		}
 	}
 }
 
-func send(event any, client interfaces.Client) {
-	select {
-	case client.Out() <- event:
-	case <-client.Done():
+// Publishes event to other server instances running the chat.
+func (c *Chat) publish(event any) {
+	serialized, err := events.Serialize(event)
+	if err != nil {
+		c.logger.WithError(err).Errorf(
+			"chat: failed to serialize event of type %T in chat '%s'", event, c.Name)
+		return
+	}
+
+	c.publishEnvelope(eventEnvelope(serialized))
+}
+
+// Publishes envelope to other server instances running the chat.
+func (c *Chat) publishEnvelope(envelope envelope) {
+	envelope.Origin = c.instanceID
+	payload, err := envelope.encode()
+	if err == nil {
+		err = c.backplane.Publish(context.Background(), c.Name, payload)
+	}
+	if err != nil {
+		c.logger.WithError(err).Errorf("chat: failed to publish to backplane in chat '%s'", c.Name)
+	}
+}
+
+// Runs function in a separate goroutine tracked by chat.
+func (c *Chat) spawn(f func()) {
+	c.goroutines.Add(1)
+	c.goroutineCount.Add(1)
+	go func() {
+		defer func() {
+			c.goroutineCount.Add(-1)
+			c.goroutines.Done()
+		}()
+		f()
+	}()
+}
+
+func (c *Chat) resetIdleTimer() {
+	if c.cfg.IdleTimeout <= 0 {
+		return
+	}
+
+	c.stopIdleTimer()
+	c.idleTimer = time.NewTimer(c.cfg.IdleTimeout)
+	c.idle = c.idleTimer.C
+}
+
+func (c *Chat) stopIdleTimer() {
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+		c.idleTimer = nil
+		c.idle = nil
 	}
 }
 
 type joinChatRequest struct {
-	Client interfaces.Client
-	Err    chan error
+	Client  interfaces.Client
+	LastSeq uint64
+	Err     chan error
 }
 
 type leaveChatRequest struct {
 	ClientID string
+	// Client which left, ignored if it was already replaced by another one.
+	Client interfaces.Client
+}
+
+type stopChatRequest struct {
+	Reason    string `json:"reason"`
+	CloseCode int    `json:"closeCode"`
 }
@@ -1,73 +1,399 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 
+	"github.com/google/uuid"
+	"github.com/shkotk/gochat/server/config"
 	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/shkotk/gochat/server/models"
+	"github.com/shkotk/gochat/server/repositories"
 	"github.com/sirupsen/logrus"
 )
 
+// Returned when chats are requested after ChatManager was shut down.
+var errShuttingDown = errors.New("server is shutting down")
+
+// Notice sent to chat members when server is shutting down.
+const shutdownNotice = "server is restarting"
+
 type ChatManager struct {
 	chats     map[string]*Chat
 	chatsLock sync.RWMutex
 
-	eventsPreProcessor interfaces.EventPreProcessor
-	logger             *logrus.Logger
+	// Set once ChatManager is shut down, no chats are run afterwards.
+	closed bool
+
+	// Unique ID of this server instance, distinguishes its backplane messages.
+	instanceID string
+
+	cfg                    config.ChatConfig
+	backplane              interfaces.Backplane
+	notifier               interfaces.Notifier
+	chatRepository         *repositories.ChatRepository
+	chatMemberRepository   *repositories.ChatMemberRepository
+	messageRepository      *repositories.MessageRepository
+	reactionRepository     *repositories.ReactionRepository
+	notificationRepository *repositories.NotificationRepository
+	eventsPreProcessor     interfaces.EventPreProcessor
+	logger                 *logrus.Logger
 }
 
+// Creates ChatManager and runs all chats stored in the database.
 func NewChatManager(
+	cfg config.Config,
 	logger *logrus.Logger,
+	backplane interfaces.Backplane,
+	notifier interfaces.Notifier,
 	eventsPreProcessor interfaces.EventPreProcessor,
+	chatRepository *repositories.ChatRepository,
+	chatMemberRepository *repositories.ChatMemberRepository,
+	messageRepository *repositories.MessageRepository,
+	reactionRepository *repositories.ReactionRepository,
+	notificationRepository *repositories.NotificationRepository,
 ) *ChatManager {
-	return &ChatManager{
-		chats:              make(map[string]*Chat),
-		eventsPreProcessor: eventsPreProcessor,
-		logger:             logger,
+	m := &ChatManager{
+		chats:                  make(map[string]*Chat),
+		instanceID:             uuid.NewString(),
+		cfg:                    cfg.Chat,
+		backplane:              backplane,
+		notifier:               notifier,
+		chatRepository:         chatRepository,
+		chatMemberRepository:   chatMemberRepository,
+		messageRepository:      messageRepository,
+		reactionRepository:     reactionRepository,
+		notificationRepository: notificationRepository,
+		eventsPreProcessor:     eventsPreProcessor,
+		logger:                 logger,
 	}
+
+	if err := m.restore(context.Background()); err != nil {
+		logger.WithError(err).Fatal("Can't restore chats")
+	}
+
+	return m
 }
 
-func (m *ChatManager) Create(chatName string) error {
+func (m *ChatManager) Create(ctx context.Context, chatName, owner string, private bool) error {
 	m.chatsLock.Lock()
 	defer m.chatsLock.Unlock()
 
-	if _, ok := m.chats[chatName]; ok {
+	if m.closed {
+		return errShuttingDown
+	}
+
+	exists, err := m.chatRepository.Exists(ctx, chatName)
+	if err != nil {
+		return err
+	}
+	if exists {
 		return fmt.Errorf("chat with name '%v' already exists", chatName)
 	}
 
-	chat := NewChat(chatName, m.eventsPreProcessor, m.logger)
-	m.chats[chatName] = chat
-	go chat.Run()
+	err = m.chatRepository.Create(ctx, models.Chat{
+		Name:    chatName,
+		Owner:   owner,
+		Private: private,
+		Members: []models.ChatMember{
+			{Username: owner, Role: models.RoleOwner},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	m.run(chatName)
 
 	return nil
 }
 
-func (m *ChatManager) List() ([]string, error) {
-	m.chatsLock.RLock()
-	defer m.chatsLock.RUnlock()
+// Creates direct chat between two users unless it already exists.
+// Returns direct chat name.
+func (m *ChatManager) OpenDirect(ctx context.Context, username, peer string) (string, error) {
+	chatName := DirectChatName(username, peer)
+
+	m.chatsLock.Lock()
+	defer m.chatsLock.Unlock()
+
+	if m.closed {
+		return "", errShuttingDown
+	}
+
+	exists, err := m.chatRepository.Exists(ctx, chatName)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return chatName, nil
+	}
+
+	err = m.chatRepository.Create(ctx, models.Chat{
+		Name:    chatName,
+		Private: true,
+		Direct:  true,
+		Members: []models.ChatMember{
+			{Username: username, Role: models.RoleMember},
+			{Username: peer, Role: models.RoleMember},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	m.run(chatName)
+
+	return chatName, nil
+}
+
+func (m *ChatManager) Delete(ctx context.Context, chatName string) error {
+	return m.stop(chatName, "chat was deleted", func() error {
+		return m.chatRepository.Delete(ctx, chatName)
+	})
+}
+
+func (m *ChatManager) Archive(ctx context.Context, chatName string) error {
+	return m.stop(chatName, "chat was archived", func() error {
+		return m.chatRepository.SetArchived(ctx, chatName, true)
+	})
+}
+
+func (m *ChatManager) AddClient(
+	ctx context.Context, client interfaces.Client, chatName string, lastSeq uint64,
+) error {
+	for attempt := 0; ; attempt++ {
+		if err := m.authorizeJoin(ctx, client.ID(), chatName); err != nil {
+			return err
+		}
+
+		chat, err := m.load(ctx, chatName)
+		if err != nil {
+			return err
+		}
+
+		err = chat.AddClient(client, lastSeq)
+		if errors.Is(err, errChatStopped) && attempt == 0 {
+			continue // chat was unloaded concurrently, retry once
+		}
 
-	chatNames := make([]string, len(m.chats))
-	i := 0
-	for key := range m.chats {
-		chatNames[i] = key
-		i++
+		return err
 	}
+}
+
+// Gets sorted usernames of users currently in chat with provided chat name.
+func (m *ChatManager) Members(ctx context.Context, chatName string) ([]string, error) {
+	for attempt := 0; ; attempt++ {
+		chat, err := m.load(ctx, chatName)
+		if err != nil {
+			return nil, err
+		}
+
+		members, err := chat.Members()
+		if errors.Is(err, errChatStopped) && attempt == 0 {
+			continue // chat was unloaded concurrently, retry once
+		}
 
-	return chatNames, nil
+		return members, err
+	}
 }
 
-func (m *ChatManager) AddClient(client interfaces.Client, chatName string) error {
+// Stops accepting new clients and stops all running chats, notifying their members
+// that server is restarting. Returns context error if chats weren't stopped before
+// context is done.
+func (m *ChatManager) Shutdown(ctx context.Context) error {
+	m.chatsLock.Lock()
+	m.closed = true
+	chats := m.chats
+	m.chats = make(map[string]*Chat)
+	m.chatsLock.Unlock()
+
+	var wg sync.WaitGroup
+	for _, chat := range chats {
+		wg.Add(1)
+		go func(chat *Chat) {
+			defer wg.Done()
+			chat.Stop(shutdownNotice, interfaces.CloseServiceRestart)
+		}(chat)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		m.logger.Infof("Stopped %d chats", len(chats))
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Gets statistics of currently loaded chats.
+func (m *ChatManager) Stats() interfaces.ChatStats {
 	m.chatsLock.RLock()
 	defer m.chatsLock.RUnlock()
 
-	chat, ok := m.chats[chatName]
-	if !ok {
+	stats := interfaces.ChatStats{LiveChats: len(m.chats)}
+	for _, chat := range m.chats {
+		stats.ChatGoroutines += chat.Goroutines()
+	}
+
+	return stats
+}
+
+// Checks that user is allowed to join the chat.
+func (m *ChatManager) authorizeJoin(ctx context.Context, username, chatName string) error {
+	chat, err := m.chatRepository.Get(ctx, chatName)
+	if err != nil {
+		return err
+	}
+	if chat == nil {
 		return fmt.Errorf("chat '%v' does not exist", chatName)
 	}
+	if chat.Archived {
+		return fmt.Errorf("chat '%v' is archived", chatName)
+	}
+	if !chat.Private {
+		return nil
+	}
 
-	if err := chat.AddClient(client); err != nil {
+	role, err := m.chatMemberRepository.GetRole(ctx, chatName, username)
+	if err != nil {
 		return err
 	}
+	if role == "" {
+		return fmt.Errorf("user '%v' is not a member of private chat '%v'", username, chatName)
+	}
 
 	return nil
 }
+
+// Loads stored chats and runs them.
+func (m *ChatManager) restore(ctx context.Context) error {
+	chats, err := m.chatRepository.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.chatsLock.Lock()
+	defer m.chatsLock.Unlock()
+
+	for _, chat := range chats {
+		if !chat.Archived {
+			m.run(chat.Name)
+		}
+	}
+
+	m.logger.Infof("Restored %d chats", len(m.chats))
+
+	return nil
+}
+
+// Gets running chat instance, loading it from the database if chat was
+// never loaded or was unloaded.
+func (m *ChatManager) load(ctx context.Context, chatName string) (*Chat, error) {
+	m.chatsLock.RLock()
+	chat, ok := m.chats[chatName]
+	m.chatsLock.RUnlock()
+	if ok && !isStopped(chat) {
+		return chat, nil
+	}
+
+	m.chatsLock.Lock()
+	defer m.chatsLock.Unlock()
+
+	if m.closed {
+		return nil, errShuttingDown
+	}
+	if chat, ok := m.chats[chatName]; ok && !isStopped(chat) {
+		return chat, nil
+	}
+
+	storedChat, err := m.chatRepository.Get(ctx, chatName)
+	if err != nil {
+		return nil, err
+	}
+	if storedChat == nil || storedChat.Archived {
+		return nil, fmt.Errorf("chat '%v' does not exist", chatName)
+	}
+
+	return m.run(chatName), nil
+}
+
+// Creates chat instance and runs it. Chat instance is removed once it's stopped.
+// Caller must hold chatsLock.
+func (m *ChatManager) run(chatName string) *Chat {
+	chat := NewChat(
+		chatName,
+		m.instanceID,
+		m.cfg,
+		m.backplane,
+		m.notifier,
+		m.eventsPreProcessor,
+		m.messageRepository,
+		m.reactionRepository,
+		m.notificationRepository,
+		m.logger)
+	m.chats[chatName] = chat
+
+	go func() {
+		chat.Run(context.Background())
+		m.forget(chat)
+	}()
+
+	return chat
+}
+
+// Removes chat instance unless it was already replaced.
+func (m *ChatManager) forget(chat *Chat) {
+	m.chatsLock.Lock()
+	defer m.chatsLock.Unlock()
+
+	if m.chats[chat.Name] == chat {
+		delete(m.chats, chat.Name)
+	}
+}
+
+// Applies stored chat change and stops chat instances run by all server instances
+// with provided reason. Chat can't be loaded while change is being applied.
+func (m *ChatManager) stop(chatName, reason string, change func() error) error {
+	m.chatsLock.Lock()
+	if err := change(); err != nil {
+		m.chatsLock.Unlock()
+		return err
+	}
+
+	chat, ok := m.chats[chatName]
+	delete(m.chats, chatName)
+	m.chatsLock.Unlock()
+
+	request := stopChatRequest{Reason: reason, CloseCode: interfaces.CloseNormal}
+	if ok {
+		chat.Stop(request.Reason, request.CloseCode)
+	}
+
+	payload, err := envelope{Origin: m.instanceID, Stop: &request}.encode()
+	if err == nil {
+		err = m.backplane.Publish(context.Background(), chatName, payload)
+	}
+	if err != nil {
+		m.logger.WithError(err).Errorf(
+			"Failed to notify other instances about stopping chat '%s'", chatName)
+	}
+
+	return nil
+}
+
+func isStopped(chat *Chat) bool {
+	select {
+	case <-chat.Done():
+		return true
+	default:
+		return false
+	}
+}
@@ -1,3 +1,31 @@
 package services
 
-// TODO
+import (
+	"context"
+	"testing"
+
+	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatManager_Shutdown_RunningChats_StopsChatsAndRejectsClients(t *testing.T) {
+	m := &ChatManager{
+		chats:  make(map[string]*Chat),
+		logger: logrus.StandardLogger(),
+	}
+	chat := newTestChat(m.cfg)
+	m.chats[chat.Name] = chat
+	go chat.Run(context.Background())
+	client := newFakeClient("jim")
+	require.Nil(t, chat.AddClient(client, 0))
+
+	err := m.Shutdown(context.Background())
+
+	require.Nil(t, err)
+	assert.Zero(t, m.Stats().LiveChats)
+	assert.Equal(t, interfaces.CloseServiceRestart, client.closeCode)
+	_, err = m.load(context.Background(), chat.Name)
+	assert.ErrorIs(t, err, errShuttingDown)
+}
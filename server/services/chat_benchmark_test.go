@@ -0,0 +1,126 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gorillaws "github.com/gorilla/websocket"
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/config"
+	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/shkotk/gochat/server/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+var benchmarkChatSizes = []int{10, 100, 500}
+
+// Measures delivering a message to every member of a chat over loopback
+// WebSocket connections, with the message serialized once per chat.
+func BenchmarkChat_Broadcast(b *testing.B) {
+	for _, size := range benchmarkChatSizes {
+		b.Run(fmt.Sprintf("members=%d", size), func(b *testing.B) {
+			chat, received := newBenchmarkChat(b, size)
+			event := newBenchmarkMessage()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				received.Add(size)
+				chat.broadcast(event)
+				received.Wait()
+			}
+		})
+	}
+}
+
+// Measures the same delivery with the message serialized by every member,
+// as a baseline for BenchmarkChat_Broadcast.
+func BenchmarkChat_BroadcastSerializedPerMember(b *testing.B) {
+	for _, size := range benchmarkChatSizes {
+		b.Run(fmt.Sprintf("members=%d", size), func(b *testing.B) {
+			chat, received := newBenchmarkChat(b, size)
+			event := newBenchmarkMessage()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				received.Add(size)
+				for _, client := range chat.members {
+					client.Send(event)
+				}
+				received.Wait()
+			}
+		})
+	}
+}
+
+func newBenchmarkMessage() *events.NewMessage {
+	return &events.NewMessage{
+		Chat:     "test",
+		Producer: "jim",
+		Time:     time.Now(),
+		Text:     strings.Repeat("lorem ipsum ", 10),
+	}
+}
+
+// Creates chat with provided number of members connected over loopback WebSocket
+// connections. Returned WaitGroup is marked done for every message members receive.
+func newBenchmarkChat(b *testing.B, size int) (*Chat, *sync.WaitGroup) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	factory := websocket.NewClientFactory(config.Config{
+		Client: config.ClientConfig{QueueSize: 16, SlowConsumerPolicy: config.DropNew},
+	}, logger)
+
+	clients := make(chan interfaces.Client)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			b.Error(err)
+			return
+		}
+
+		client := factory.NewClient(r.URL.Query().Get("id"), conn)
+		go client.Run()
+		clients <- client
+	}))
+
+	chat := newTestChat(config.ChatConfig{})
+	received := &sync.WaitGroup{}
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	for i := 0; i < size; i++ {
+		conn, _, err := gorillaws.DefaultDialer.Dial(fmt.Sprintf("%s?id=user%d", url, i), nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		go func() {
+			defer conn.Close()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+				received.Done()
+			}
+		}()
+
+		client := <-clients
+		chat.members[client.ID()] = client
+	}
+
+	b.Cleanup(func() {
+		for _, client := range chat.members {
+			client.Close(interfaces.CloseNormal, "")
+			<-client.Done()
+		}
+		server.Close()
+	})
+
+	return chat, received
+}
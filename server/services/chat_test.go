@@ -1,3 +1,402 @@
 package services
 
-// TODO
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/config"
+	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/shkotk/gochat/server/websocket"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClient struct {
+	id string
+
+	in   chan any
+	out  chan any
+	done chan struct{}
+
+	closeOnce sync.Once
+	closeCode int
+}
+
+func newFakeClient(id string) *fakeClient {
+	return &fakeClient{
+		id:   id,
+		in:   make(chan any),
+		out:  make(chan any, 16),
+		done: make(chan struct{}),
+	}
+}
+
+func (c *fakeClient) ID() string            { return c.id }
+func (c *fakeClient) In() <-chan any        { return c.in }
+func (c *fakeClient) Send(event any)        { c.out <- event }
+func (c *fakeClient) Done() <-chan struct{} { return c.done }
+
+// Waits for the next event sent to client, unwrapping frames.
+func (c *fakeClient) next(t *testing.T) any {
+	t.Helper()
+
+	select {
+	case event := <-c.out:
+		if frame, ok := event.(*websocket.Frame); ok {
+			return frame.Event()
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event was sent to client")
+		return nil
+	}
+}
+
+func (c *fakeClient) Close(code int, _ string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		close(c.done)
+	})
+}
+
+func newTestChat(cfg config.ChatConfig) *Chat {
+	return newTestChatInstance(cfg, "instance", NewMemoryBackplane(logrus.StandardLogger()))
+}
+
+func newTestChatInstance(cfg config.ChatConfig, instanceID string, backplane interfaces.Backplane) *Chat {
+	return NewChat("test", instanceID, cfg, backplane, nil,
+		newTestProcessorChain(nil,
+			newTestEventRateLimiter(), NewEventValidator(nil, nil), NewCommandRegistry(nil, nil)),
+		nil, nil, nil, logrus.StandardLogger())
+}
+
+func TestChat_Run_NoMembersForIdleTimeout_Stops(t *testing.T) {
+	chat := newTestChat(config.ChatConfig{IdleTimeout: 10 * time.Millisecond})
+
+	go chat.Run(context.Background())
+
+	select {
+	case <-chat.Done():
+	case <-time.After(time.Second):
+		t.Fatal("idle chat was not stopped")
+	}
+	chat.Wait()
+	assert.Zero(t, chat.Goroutines())
+}
+
+func TestChat_AddClient_StoppedChat_ReturnsErrChatStopped(t *testing.T) {
+	chat := newTestChat(config.ChatConfig{})
+	go chat.Run(context.Background())
+	chat.Stop("", interfaces.CloseNormal)
+
+	err := chat.AddClient(newFakeClient("jim"), 0)
+
+	assert.ErrorIs(t, err, errChatStopped)
+}
+
+func TestChat_Stop_ChatWithMembers_NotifiesAndClosesMembers(t *testing.T) {
+	chat := newTestChat(config.ChatConfig{})
+	go chat.Run(context.Background())
+	client := newFakeClient("jim")
+	require.Nil(t, chat.AddClient(client, 0))
+
+	chat.Stop("chat was deleted", interfaces.CloseNormal)
+
+	assert.Zero(t, chat.Goroutines())
+	select {
+	case <-client.Done():
+	default:
+		t.Fatal("client was not closed")
+	}
+	assert.Equal(t, interfaces.CloseNormal, client.closeCode)
+
+	var notice *events.SystemMessage
+	for len(client.out) > 0 {
+		if event, ok := client.next(t).(*events.SystemMessage); ok {
+			notice = event
+		}
+	}
+	require.NotNil(t, notice)
+	assert.Equal(t, "chat was deleted", notice.Text)
+	assert.Equal(t, "test", notice.Chat)
+}
+
+func TestChat_Run_CancelledContext_Stops(t *testing.T) {
+	chat := newTestChat(config.ChatConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go chat.Run(ctx)
+
+	cancel()
+
+	select {
+	case <-chat.Done():
+	case <-time.After(time.Second):
+		t.Fatal("chat was not stopped")
+	}
+}
+
+func TestChat_AddClient_ChatRunOnSeveralInstances_NotifiesRemoteMembers(t *testing.T) {
+	backplane := NewMemoryBackplane(logrus.StandardLogger())
+	first := newTestChatInstance(config.ChatConfig{}, "first", backplane)
+	second := newTestChatInstance(config.ChatConfig{}, "second", backplane)
+	go first.Run(context.Background())
+	go second.Run(context.Background())
+	defer first.Stop("", interfaces.CloseNormal)
+	defer second.Stop("", interfaces.CloseNormal)
+	jim := newFakeClient("jim")
+	require.Nil(t, first.AddClient(jim, 0))
+
+	require.Nil(t, second.AddClient(newFakeClient("pam"), 0))
+
+	assert.Equal(t, []string{"jim"}, jim.next(t).(*events.Members).Usernames)
+	assert.Equal(t, "pam", jim.next(t).(*events.MemberJoined).Username)
+	members, err := first.Members()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"jim", "pam"}, members)
+}
+
+func TestChat_Run_StopPublishedByOtherInstance_StopsAndClosesMembers(t *testing.T) {
+	backplane := NewMemoryBackplane(logrus.StandardLogger())
+	chat := newTestChatInstance(config.ChatConfig{}, "first", backplane)
+	go chat.Run(context.Background())
+	client := newFakeClient("jim")
+	require.Nil(t, chat.AddClient(client, 0))
+	payload, err := envelope{
+		Origin: "second",
+		Stop:   &stopChatRequest{Reason: "chat was deleted", CloseCode: interfaces.CloseNormal},
+	}.encode()
+	require.Nil(t, err)
+
+	require.Nil(t, backplane.Publish(context.Background(), chat.Name, payload))
+
+	select {
+	case <-chat.Done():
+	case <-time.After(time.Second):
+		t.Fatal("chat was not stopped")
+	}
+	chat.Wait()
+	<-client.Done()
+	assert.Equal(t, interfaces.CloseNormal, client.closeCode)
+}
+
+func TestChat_AddClient_ChatWithMembers_SendsSnapshotAndNotifiesMembers(t *testing.T) {
+	chat := newTestChat(config.ChatConfig{})
+	go chat.Run(context.Background())
+	defer chat.Stop("", interfaces.CloseNormal)
+	jim := newFakeClient("jim")
+	require.Nil(t, chat.AddClient(jim, 0))
+	jim.next(t)
+	pam := newFakeClient("pam")
+
+	require.Nil(t, chat.AddClient(pam, 0))
+
+	assert.Equal(t, "pam", jim.next(t).(*events.MemberJoined).Username)
+	assert.Equal(t, []string{"jim", "pam"}, pam.next(t).(*events.Members).Usernames)
+}
+
+func TestChat_Run_MemberLeft_NotifiesMembers(t *testing.T) {
+	chat := newTestChat(config.ChatConfig{})
+	go chat.Run(context.Background())
+	defer chat.Stop("", interfaces.CloseNormal)
+	jim := newFakeClient("jim")
+	require.Nil(t, chat.AddClient(jim, 0))
+	pam := newFakeClient("pam")
+	require.Nil(t, chat.AddClient(pam, 0))
+	jim.next(t)
+	jim.next(t)
+
+	pam.Close(interfaces.CloseNormal, "")
+
+	assert.Equal(t, "pam", jim.next(t).(*events.MemberLeft).Username)
+	members, err := chat.Members()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"jim"}, members)
+}
+
+func TestChat_Run_OtherInstanceHasMembers_SyncsMembers(t *testing.T) {
+	backplane := NewMemoryBackplane(logrus.StandardLogger())
+	first := newTestChatInstance(config.ChatConfig{}, "first", backplane)
+	go first.Run(context.Background())
+	defer first.Stop("", interfaces.CloseNormal)
+	require.Nil(t, first.AddClient(newFakeClient("jim"), 0))
+	second := newTestChatInstance(config.ChatConfig{}, "second", backplane)
+
+	go second.Run(context.Background())
+	defer second.Stop("", interfaces.CloseNormal)
+
+	synced := func() bool {
+		members, err := second.Members()
+		return err == nil && len(members) == 1 && members[0] == "jim"
+	}
+
+	assert.Eventually(t, synced, time.Second, 10*time.Millisecond)
+}
+
+func TestChat_Run_FrequentTyping_RelaysOnceToOtherMembers(t *testing.T) {
+	chat := newTestChat(config.ChatConfig{})
+	go chat.Run(context.Background())
+	defer chat.Stop("", interfaces.CloseNormal)
+	jim := newFakeClient("jim")
+	require.Nil(t, chat.AddClient(jim, 0))
+	pam := newFakeClient("pam")
+	require.Nil(t, chat.AddClient(pam, 0))
+	jim.next(t)
+	jim.next(t)
+	pam.next(t)
+
+	pam.in <- &events.Typing{}
+	pam.in <- &events.Typing{}
+	pam.Close(interfaces.CloseNormal, "")
+
+	typing := jim.next(t).(*events.Typing)
+	assert.Equal(t, "pam", typing.Producer)
+	assert.Equal(t, typing.Time.Add(typingTimeout), typing.Until)
+	assert.IsType(t, &events.MemberLeft{}, jim.next(t))
+	assert.Empty(t, pam.out)
+}
+
+func TestChat_Run_WhoCommand_RepliesToCallerWithMembers(t *testing.T) {
+	chat := newTestChat(config.ChatConfig{})
+	go chat.Run(context.Background())
+	defer chat.Stop("", interfaces.CloseNormal)
+	jim := newFakeClient("jim")
+	require.Nil(t, chat.AddClient(jim, 0))
+	pam := newFakeClient("pam")
+	require.Nil(t, chat.AddClient(pam, 0))
+	jim.next(t)
+	jim.next(t)
+	pam.next(t)
+
+	pam.in <- &events.NewMessage{Text: "/who"}
+
+	reply := pam.next(t).(*events.SystemMessage)
+	assert.Equal(t, "test", reply.Chat)
+	assert.Equal(t, "2 in chat: jim, pam", reply.Text)
+	assert.Empty(t, jim.out)
+}
+
+func TestChat_Run_MemberKicked_NotifiesMembersAndClosesKickedClient(t *testing.T) {
+	chat := newTestChat(config.ChatConfig{})
+	go chat.Run(context.Background())
+	defer chat.Stop("", interfaces.CloseNormal)
+	jim := newFakeClient("jim")
+	require.Nil(t, chat.AddClient(jim, 0))
+	pam := newFakeClient("pam")
+	require.Nil(t, chat.AddClient(pam, 0))
+	jim.next(t)
+	jim.next(t)
+	pam.next(t)
+
+	chat.events <- &events.MemberKicked{Chat: "test", Username: "pam", KickedBy: "jim"}
+
+	assert.Equal(t, "pam", jim.next(t).(*events.MemberKicked).Username)
+	assert.Equal(t, "pam", pam.next(t).(*events.MemberKicked).Username)
+	assert.Equal(t, "pam", jim.next(t).(*events.MemberLeft).Username)
+	assert.Equal(t, interfaces.CloseKicked, pam.closeCode)
+}
+
+func TestChat_Run_MemberMuted_DropsEventsOfMutedUser(t *testing.T) {
+	chat := newTestChat(config.ChatConfig{})
+	go chat.Run(context.Background())
+	defer chat.Stop("", interfaces.CloseNormal)
+	jim := newFakeClient("jim")
+	require.Nil(t, chat.AddClient(jim, 0))
+	pam := newFakeClient("pam")
+	require.Nil(t, chat.AddClient(pam, 0))
+	jim.next(t)
+	jim.next(t)
+	pam.next(t)
+
+	chat.events <- &events.MemberMuted{
+		Chat: "test", Username: "pam", MutedBy: "jim", Until: time.Now().Add(time.Minute)}
+	assert.Equal(t, "pam", jim.next(t).(*events.MemberMuted).Username)
+	assert.Equal(t, "pam", pam.next(t).(*events.MemberMuted).Username)
+
+	pam.in <- &events.Typing{}
+
+	assert.Equal(t, "you are muted for 1m0s", pam.next(t).(*events.Error).Text)
+	assert.Empty(t, jim.out)
+}
+
+func TestChat_Run_MemberMuted_RejectsCommandsOfMutedUser(t *testing.T) {
+	chat := newTestChat(config.ChatConfig{})
+	go chat.Run(context.Background())
+	defer chat.Stop("", interfaces.CloseNormal)
+	jim := newFakeClient("jim")
+	require.Nil(t, chat.AddClient(jim, 0))
+	jim.next(t)
+	chat.events <- &events.MemberMuted{
+		Chat: "test", Username: "jim", MutedBy: "michael", Until: time.Now().Add(time.Minute)}
+	jim.next(t)
+
+	jim.in <- &events.NewMessage{Text: "/who"}
+
+	assert.Equal(t, "you are muted for 1m0s", jim.next(t).(*events.Error).Text)
+	assert.Empty(t, jim.out, "command was executed")
+}
+
+func TestChat_AddClient_ResumingUserAlreadyInChat_ReplacesPreviousClient(t *testing.T) {
+	chat := newTestChat(config.ChatConfig{})
+	go chat.Run(context.Background())
+	defer chat.Stop("", interfaces.CloseNormal)
+	jim := newFakeClient("jim")
+	require.Nil(t, chat.AddClient(jim, 0))
+	pam := newFakeClient("pam")
+	require.Nil(t, chat.AddClient(pam, 0))
+	jim.next(t)
+	jim.next(t)
+	pam.next(t)
+	reconnected := newFakeClient("pam")
+
+	require.Nil(t, chat.AddClient(reconnected, 1))
+
+	select {
+	case <-pam.Done():
+	case <-time.After(time.Second):
+		t.Fatal("previous client was not closed")
+	}
+	assert.Equal(t, interfaces.CloseNormal, pam.closeCode)
+	assert.Equal(t, []string{"jim", "pam"}, reconnected.next(t).(*events.Members).Usernames)
+
+	reconnected.in <- &events.Typing{}
+
+	assert.Equal(t, "pam", jim.next(t).(*events.Typing).Producer, "pam left instead of reconnecting")
+	members, err := chat.Members()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"jim", "pam"}, members)
+}
+
+func TestChat_AddClient_ConcurrentFreshJoinsOfUser_AcceptsOneClient(t *testing.T) {
+	chat := newTestChat(config.ChatConfig{})
+	go chat.Run(context.Background())
+	defer chat.Stop("", interfaces.CloseNormal)
+	clients := []*fakeClient{newFakeClient("jim"), newFakeClient("jim")}
+
+	errs := make(chan error, len(clients))
+	for _, client := range clients {
+		client := client
+		go func() { errs <- chat.AddClient(client, 0) }()
+	}
+
+	failed := 0
+	for range clients {
+		if err := <-errs; err != nil {
+			failed++
+		}
+	}
+	assert.Equal(t, 1, failed)
+	for _, client := range clients {
+		select {
+		case <-client.Done():
+			t.Fatal("client was closed")
+		default:
+		}
+	}
+	members, err := chat.Members()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"jim"}, members)
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/sirupsen/logrus"
+)
+
+// Keeps track of connected clients by their user, so that they can be reached
+// regardless of chats they are in. Clients are removed once they are done.
+// Events sent with Notify reach clients connected to any server instance,
+// since registry subscribes to backplane topics of users it has clients of.
+type ClientRegistry struct {
+	clients map[string]map[interfaces.Client]struct{}
+	// Cancels backplane subscription of each user with registered clients.
+	subscriptions map[string]context.CancelFunc
+	lock          sync.Mutex
+
+	// Set once registry is shut down, clients registered afterwards are closed at once.
+	closed bool
+
+	backplane interfaces.Backplane
+	logger    *logrus.Logger
+}
+
+func NewClientRegistry(backplane interfaces.Backplane, logger *logrus.Logger) *ClientRegistry {
+	return &ClientRegistry{
+		clients:       make(map[string]map[interfaces.Client]struct{}),
+		subscriptions: make(map[string]context.CancelFunc),
+		backplane:     backplane,
+		logger:        logger,
+	}
+}
+
+// Adds client to registry until it's done.
+func (r *ClientRegistry) Register(client interfaces.Client) {
+	r.lock.Lock()
+	if r.closed {
+		r.lock.Unlock()
+		client.Close(interfaces.CloseServiceRestart, shutdownNotice)
+		return
+	}
+
+	clients, ok := r.clients[client.ID()]
+	if !ok {
+		clients = make(map[interfaces.Client]struct{})
+		r.clients[client.ID()] = clients
+		r.subscribe(client.ID())
+	}
+	clients[client] = struct{}{}
+	r.lock.Unlock()
+
+	go func() {
+		<-client.Done()
+		r.unregister(client)
+	}()
+}
+
+// Sends event to all clients of the user connected to any server instance.
+func (r *ClientRegistry) Notify(ctx context.Context, username string, event any) error {
+	serialized, err := events.Serialize(event)
+	if err != nil {
+		return err
+	}
+
+	payload, err := eventEnvelope(serialized).encode()
+	if err != nil {
+		return err
+	}
+
+	return r.backplane.Publish(ctx, userTopic(username), payload)
+}
+
+// Closes all registered clients and waits until they're done.
+// Returns context error if clients weren't done before context is done.
+func (r *ClientRegistry) Shutdown(ctx context.Context) error {
+	r.lock.Lock()
+	r.closed = true
+	clients := []interfaces.Client{}
+	for _, userClients := range r.clients {
+		for client := range userClients {
+			clients = append(clients, client)
+		}
+	}
+	r.lock.Unlock()
+
+	for _, client := range clients {
+		client.Close(interfaces.CloseServiceRestart, shutdownNotice)
+	}
+
+	for _, client := range clients {
+		select {
+		case <-client.Done():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	r.logger.Infof("Closed %d clients", len(clients))
+
+	return nil
+}
+
+func (r *ClientRegistry) unregister(client interfaces.Client) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	clients := r.clients[client.ID()]
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(r.clients, client.ID())
+		r.subscriptions[client.ID()]()
+		delete(r.subscriptions, client.ID())
+	}
+}
+
+// Starts delivering events published to the user topic to their clients.
+// Should be called with lock held.
+func (r *ClientRegistry) subscribe(username string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.subscriptions[username] = cancel
+	payloads := r.backplane.Subscribe(ctx, userTopic(username))
+
+	go func() {
+		for payload := range payloads {
+			received, err := decodeEnvelope(payload)
+			if err != nil {
+				r.logger.WithError(err).Errorf(
+					"client registry: failed to decode backplane message for '%s'", username)
+				continue
+			}
+
+			event, err := events.Parse(received.serializedEvent())
+			if err != nil {
+				r.logger.WithError(err).Errorf(
+					"client registry: failed to parse event for '%s'", username)
+				continue
+			}
+
+			r.lock.Lock()
+			for client := range r.clients[username] {
+				client.Send(event)
+			}
+			r.lock.Unlock()
+		}
+	}()
+}
+
+// Gets backplane topic of events sent to the user. Unlike chat names, it
+// contains a slash, so it never clashes with a chat topic.
+func userTopic(username string) string {
+	return "user/" + username
+}
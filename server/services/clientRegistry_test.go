@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRegistry_Shutdown_RegisteredClients_ClosesClients(t *testing.T) {
+	registry := NewClientRegistry(NewMemoryBackplane(logrus.StandardLogger()), logrus.StandardLogger())
+	client := newFakeClient("jim")
+	registry.Register(client)
+
+	err := registry.Shutdown(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, interfaces.CloseServiceRestart, client.closeCode)
+}
+
+func TestClientRegistry_Register_AfterShutdown_ClosesClient(t *testing.T) {
+	registry := NewClientRegistry(NewMemoryBackplane(logrus.StandardLogger()), logrus.StandardLogger())
+	registry.Shutdown(context.Background())
+	client := newFakeClient("jim")
+
+	registry.Register(client)
+
+	select {
+	case <-client.Done():
+	case <-time.After(time.Second):
+		t.Fatal("client was not closed")
+	}
+	assert.Equal(t, interfaces.CloseServiceRestart, client.closeCode)
+}
+
+func TestClientRegistry_Notify_RegisteredUser_SendsEventToAllUserClients(t *testing.T) {
+	backplane := NewMemoryBackplane(logrus.StandardLogger())
+	registry := NewClientRegistry(backplane, logrus.StandardLogger())
+	first, second, other := newFakeClient("jim"), newFakeClient("jim"), newFakeClient("pam")
+	registry.Register(first)
+	registry.Register(second)
+	registry.Register(other)
+
+	err := registry.Notify(
+		context.Background(), "jim", &events.Mentioned{Chat: "test", Producer: "pam"})
+
+	require.Nil(t, err)
+	for _, client := range []*fakeClient{first, second} {
+		mentioned, ok := client.next(t).(*events.Mentioned)
+		require.True(t, ok)
+		assert.Equal(t, "pam", mentioned.Producer)
+	}
+	assert.Empty(t, other.out)
+}
+
+func TestClientRegistry_Notify_ClientsDone_DropsEvent(t *testing.T) {
+	backplane := NewMemoryBackplane(logrus.StandardLogger())
+	registry := NewClientRegistry(backplane, logrus.StandardLogger())
+	client := newFakeClient("jim")
+	registry.Register(client)
+	client.Close(interfaces.CloseNormal, "")
+
+	assert.Eventually(t, func() bool {
+		registry.lock.Lock()
+		defer registry.lock.Unlock()
+		return len(registry.subscriptions) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	err := registry.Notify(context.Background(), "jim", &events.Mentioned{})
+
+	assert.Nil(t, err)
+	assert.Empty(t, client.out)
+}
+
+func TestClientRegistry_Notify_RegisteredUser_PublishesEventEnvelope(t *testing.T) {
+	backplane := NewMemoryBackplane(logrus.StandardLogger())
+	registry := NewClientRegistry(backplane, logrus.StandardLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	payloads := backplane.Subscribe(ctx, userTopic("jim"))
+
+	err := registry.Notify(
+		context.Background(), "jim", &events.Mentioned{Chat: "test", Producer: "pam"})
+
+	require.Nil(t, err)
+	received, err := decodeEnvelope(<-payloads)
+	require.Nil(t, err)
+	event, err := events.Parse(received.serializedEvent())
+	require.Nil(t, err)
+	assert.Equal(t, "pam", event.(*events.Mentioned).Producer)
+}
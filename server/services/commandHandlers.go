@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/models"
+	"github.com/shkotk/gochat/server/repositories"
+)
+
+// Period user is muted for unless /mute sets another one.
+const defaultMuteDuration = 10 * time.Minute
+
+// Posts message describing caller's action.
+type meCommand struct{}
+
+func (meCommand) Name() string { return "me" }
+
+func (meCommand) Help() string { return "<action> - post message describing your action" }
+
+func (meCommand) Execute(call CommandCall) ([]any, error) {
+	if call.Args == "" {
+		return nil, eventRejectedError{"usage: /me <action>"}
+	}
+
+	call.Message.Text = call.Args
+	call.Message.Action = true
+
+	return []any{call.Message}, nil
+}
+
+// Shows chat topic or lets moderators change it.
+type topicCommand struct {
+	chatRepository       *repositories.ChatRepository
+	chatMemberRepository *repositories.ChatMemberRepository
+}
+
+func (topicCommand) Name() string { return "topic" }
+
+func (topicCommand) Help() string {
+	return "[topic] - show chat topic or set it, '-' clears topic"
+}
+
+func (c topicCommand) Execute(call CommandCall) ([]any, error) {
+	chatName := call.Message.Chat
+	if call.Args == "" {
+		chat, err := c.chatRepository.Get(context.Background(), chatName)
+		if err != nil {
+			return nil, err
+		}
+		if chat == nil || chat.Topic == "" {
+			call.Reply("chat has no topic")
+		} else {
+			call.Reply(fmt.Sprintf("topic: %s", chat.Topic))
+		}
+		return nil, nil
+	}
+
+	role, err := c.chatMemberRepository.GetRole(context.Background(), chatName, call.Caller.ID())
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanModerate() {
+		return nil, eventRejectedError{"only chat moderators can change topic"}
+	}
+
+	topic := call.Args
+	if topic == "-" {
+		topic = ""
+	}
+	if err := c.chatRepository.SetTopic(context.Background(), chatName, topic); err != nil {
+		return nil, err
+	}
+
+	return []any{&events.TopicChanged{
+		Chat:     chatName,
+		Producer: call.Message.Producer,
+		Time:     call.Message.Time,
+		Topic:    topic,
+	}}, nil
+}
+
+// Lists users in chat.
+type whoCommand struct{}
+
+func (whoCommand) Name() string { return "who" }
+
+func (whoCommand) Help() string { return "- list users in chat" }
+
+func (whoCommand) Execute(call CommandCall) ([]any, error) {
+	return []any{chatAction(func(chat *Chat) {
+		usernames := chat.presence.usernames()
+		call.Reply(fmt.Sprintf("%d in chat: %s", len(usernames), strings.Join(usernames, ", ")))
+	})}, nil
+}
+
+// Disconnects user from chat.
+type kickCommand struct {
+	chatMemberRepository *repositories.ChatMemberRepository
+}
+
+func (kickCommand) Name() string { return "kick" }
+
+func (kickCommand) Help() string { return "<username> - disconnect user from chat" }
+
+func (c kickCommand) Execute(call CommandCall) ([]any, error) {
+	username := call.Args
+	if username == "" || strings.ContainsRune(username, ' ') {
+		return nil, eventRejectedError{"usage: /kick <username>"}
+	}
+
+	err := authorizeModeration(c.chatMemberRepository, call.Message.Chat, call.Caller.ID(), username)
+	if err != nil {
+		return nil, err
+	}
+
+	return []any{&events.MemberKicked{
+		Chat:     call.Message.Chat,
+		Username: username,
+		KickedBy: call.Message.Producer,
+		Time:     call.Message.Time,
+	}}, nil
+}
+
+// Stops user from posting to chat for a while.
+type muteCommand struct {
+	chatMemberRepository *repositories.ChatMemberRepository
+}
+
+func (muteCommand) Name() string { return "mute" }
+
+func (muteCommand) Help() string {
+	return fmt.Sprintf("<username> [duration] - stop user from posting, for %v by default",
+		defaultMuteDuration)
+}
+
+func (c muteCommand) Execute(call CommandCall) ([]any, error) {
+	args := strings.Fields(call.Args)
+	if len(args) == 0 || len(args) > 2 {
+		return nil, eventRejectedError{"usage: /mute <username> [duration]"}
+	}
+
+	duration := defaultMuteDuration
+	if len(args) == 2 {
+		parsed, err := time.ParseDuration(args[1])
+		if err != nil || parsed <= 0 {
+			return nil, eventRejectedError{fmt.Sprintf(
+				"'%s' is not a valid duration, try 30s, 10m or 1h", args[1])}
+		}
+		duration = parsed
+	}
+
+	err := authorizeModeration(c.chatMemberRepository, call.Message.Chat, call.Caller.ID(), args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return []any{&events.MemberMuted{
+		Chat:     call.Message.Chat,
+		Username: args[0],
+		MutedBy:  call.Message.Producer,
+		Time:     call.Message.Time,
+		Until:    call.Message.Time.Add(duration),
+	}}, nil
+}
+
+// Checks that moderator can take action against user. Only chat owner can take
+// action against other moderators, nobody can take it against owner or themselves.
+func authorizeModeration(
+	chatMemberRepository *repositories.ChatMemberRepository,
+	chatName, moderator, username string,
+) error {
+	if moderator == username {
+		return eventRejectedError{"you can't do that to yourself"}
+	}
+
+	role, err := chatMemberRepository.GetRole(context.Background(), chatName, moderator)
+	if err != nil {
+		return err
+	}
+	if !role.CanModerate() {
+		return eventRejectedError{"only chat moderators can do that"}
+	}
+
+	targetRole, err := chatMemberRepository.GetRole(context.Background(), chatName, username)
+	if err != nil {
+		return err
+	}
+	if targetRole == models.RoleOwner || (targetRole.CanModerate() && role != models.RoleOwner) {
+		return eventRejectedError{fmt.Sprintf("you can't do that to %s '%s'", targetRole, username)}
+	}
+
+	return nil
+}
+
+// Lists registered commands.
+type helpCommand struct {
+	registry *CommandRegistry
+}
+
+func (helpCommand) Name() string { return "help" }
+
+func (helpCommand) Help() string { return "- list available commands" }
+
+func (c helpCommand) Execute(call CommandCall) ([]any, error) {
+	lines := make([]string, 0, len(c.registry.names)+1)
+	lines = append(lines, "commands:")
+	for _, name := range c.registry.names {
+		lines = append(lines, fmt.Sprintf(
+			"%s%s %s", commandPrefix, name, c.registry.handlers[name].Help()))
+	}
+	call.Reply(strings.Join(lines, "\n"))
+
+	return nil, nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/shkotk/gochat/server/repositories"
+)
+
+// Prefix of messages invoking commands. Messages starting with doubled prefix
+// are posted as is, with one prefix removed.
+const commandPrefix = "/"
+
+// Handles slash command invoked by chat member.
+type CommandHandler interface {
+	// Name command is invoked by, without prefix.
+	Name() string
+
+	// Command arguments and what it does, shown by /help.
+	Help() string
+
+	// Executes command. Returns events to pass to chat, replies private to
+	// the caller are sent with CommandCall.Reply instead.
+	Execute(call CommandCall) ([]any, error)
+}
+
+// Invocation of slash command.
+type CommandCall struct {
+	// Message command was invoked with, stamped with producer and time.
+	Message *events.NewMessage
+	// Text following command name, trimmed.
+	Args   string
+	Caller interfaces.Client
+}
+
+// Sends text to the caller only.
+func (c CommandCall) Reply(text string) {
+	c.Caller.Send(&events.SystemMessage{Chat: c.Message.Chat, Text: text, Time: time.Now()})
+}
+
+// Internal event run within chat loop, lets commands access chat state.
+// It's never delivered to clients or other server instances.
+type chatAction func(chat *Chat)
+
+// Dispatches messages invoking slash commands to registered handlers instead
+// of posting them.
+type CommandRegistry struct {
+	handlers map[string]CommandHandler
+	// Handler names in order of registration.
+	names []string
+}
+
+// Creates registry with built-in commands registered.
+func NewCommandRegistry(
+	chatRepository *repositories.ChatRepository,
+	chatMemberRepository *repositories.ChatMemberRepository,
+) *CommandRegistry {
+	r := &CommandRegistry{handlers: make(map[string]CommandHandler)}
+	r.Register(
+		meCommand{},
+		topicCommand{chatRepository, chatMemberRepository},
+		whoCommand{},
+		kickCommand{chatMemberRepository},
+		muteCommand{chatMemberRepository},
+		helpCommand{r},
+	)
+
+	return r
+}
+
+// Registers command handlers. Panics if command with the same name is already registered.
+func (r *CommandRegistry) Register(handlers ...CommandHandler) {
+	for _, handler := range handlers {
+		if _, ok := r.handlers[handler.Name()]; ok {
+			panic(fmt.Sprintf("command '%s' is already registered", handler.Name()))
+		}
+		r.handlers[handler.Name()] = handler
+		r.names = append(r.names, handler.Name())
+	}
+}
+
+func (r *CommandRegistry) Name() string { return "commands" }
+
+func (r *CommandRegistry) Required() bool { return true }
+
+func (r *CommandRegistry) Process(event any, producer interfaces.Client) ([]any, error) {
+	message, ok := event.(*events.NewMessage)
+	if !ok || !strings.HasPrefix(message.Text, commandPrefix) {
+		return []any{event}, nil
+	}
+	if strings.HasPrefix(message.Text, commandPrefix+commandPrefix) {
+		message.Text = strings.TrimPrefix(message.Text, commandPrefix)
+		return []any{event}, nil
+	}
+
+	name, args, _ := strings.Cut(strings.TrimPrefix(message.Text, commandPrefix), " ")
+	handler, ok := r.handlers[name]
+	if !ok {
+		return nil, eventRejectedError{fmt.Sprintf(
+			"unknown command '%s%s', see %shelp", commandPrefix, name, commandPrefix)}
+	}
+
+	return handler.Execute(CommandCall{
+		Message: message,
+		Args:    strings.TrimSpace(args),
+		Caller:  producer,
+	})
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandRegistry_Process_RegularMessage_PassesMessage(t *testing.T) {
+	message := &events.NewMessage{Text: "hi /me"}
+
+	processed, err := NewCommandRegistry(nil, nil).Process(message, newFakeClient("jim"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []any{message}, processed)
+	assert.Equal(t, "hi /me", message.Text)
+}
+
+func TestCommandRegistry_Process_EscapedPrefix_PostsMessageWithoutCommand(t *testing.T) {
+	message := &events.NewMessage{Text: "//me is not a command"}
+
+	processed, err := NewCommandRegistry(nil, nil).Process(message, newFakeClient("jim"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []any{message}, processed)
+	assert.Equal(t, "/me is not a command", message.Text)
+	assert.False(t, message.Action)
+}
+
+func TestCommandRegistry_Process_UnknownCommand_ReturnsError(t *testing.T) {
+	_, err := NewCommandRegistry(nil, nil).Process(
+		&events.NewMessage{Text: "/dance now"}, newFakeClient("jim"))
+
+	assert.ErrorIs(t, err, eventRejectedError{"unknown command '/dance', see /help"})
+}
+
+func TestCommandRegistry_Process_Me_PostsActionMessage(t *testing.T) {
+	message := &events.NewMessage{Text: "/me  waves "}
+
+	processed, err := NewCommandRegistry(nil, nil).Process(message, newFakeClient("jim"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []any{message}, processed)
+	assert.Equal(t, "waves", message.Text)
+	assert.True(t, message.Action)
+}
+
+func TestCommandRegistry_Process_InvalidArguments_ReturnsError(t *testing.T) {
+	tests := []string{
+		"/me",
+		"/kick",
+		"/kick jim pam",
+		"/mute",
+		"/mute pam forever",
+		"/mute pam -1m",
+	}
+
+	for _, text := range tests {
+		_, err := NewCommandRegistry(nil, nil).Process(
+			&events.NewMessage{Text: text}, newFakeClient("jim"))
+
+		assert.ErrorAs(t, err, &eventRejectedError{}, "'%s' was not rejected", text)
+	}
+}
+
+func TestCommandRegistry_Process_Help_RepliesToCallerOnly(t *testing.T) {
+	caller := newFakeClient("jim")
+
+	processed, err := NewCommandRegistry(nil, nil).Process(
+		&events.NewMessage{Chat: "general", Text: "/help"}, caller)
+
+	require.Nil(t, err)
+	assert.Empty(t, processed)
+	reply := caller.next(t).(*events.SystemMessage)
+	assert.Equal(t, "general", reply.Chat)
+	for _, name := range []string{"me", "topic", "who", "kick", "mute", "help"} {
+		assert.Contains(t, reply.Text, "\n/"+name+" ")
+	}
+}
+
+type echoCommand struct{}
+
+func (echoCommand) Name() string { return "echo" }
+
+func (echoCommand) Help() string { return "<text> - reply with text" }
+
+func (echoCommand) Execute(call CommandCall) ([]any, error) {
+	call.Reply(call.Args)
+	return nil, nil
+}
+
+func TestCommandRegistry_Register_NewCommand_DispatchesToIt(t *testing.T) {
+	registry := NewCommandRegistry(nil, nil)
+	registry.Register(echoCommand{})
+	caller := newFakeClient("jim")
+
+	processed, err := registry.Process(&events.NewMessage{Text: "/echo hello there"}, caller)
+
+	require.Nil(t, err)
+	assert.Empty(t, processed)
+	assert.Equal(t, "hello there", caller.next(t).(*events.SystemMessage).Text)
+}
+
+func TestCommandRegistry_Register_DuplicateCommand_Panics(t *testing.T) {
+	registry := NewCommandRegistry(nil, nil)
+
+	assert.PanicsWithValue(t, "command 'me' is already registered", func() {
+		registry.Register(meCommand{})
+	})
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"sort"
+	"strings"
+)
+
+const directChatPrefix = "dm:"
+
+// Gets name of direct chat between two users. Name is the same regardless of
+// arguments order and can't clash with group chat names, as those can't contain ':'.
+func DirectChatName(username, peer string) string {
+	usernames := []string{username, peer}
+	sort.Strings(usernames)
+	return directChatPrefix + strings.Join(usernames, ":")
+}
+
+// Gets the other participant of direct chat with provided name.
+func DirectChatPeer(chatName, username string) string {
+	usernames := strings.Split(strings.TrimPrefix(chatName, directChatPrefix), ":")
+	if usernames[0] == username && len(usernames) > 1 {
+		return usernames[1]
+	}
+	return usernames[0]
+}
@@ -0,0 +1,19 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectChatName_AnyArgumentsOrder_ReturnsSameName(t *testing.T) {
+	assert.Equal(t, "dm:jim:pam", DirectChatName("jim", "pam"))
+	assert.Equal(t, "dm:jim:pam", DirectChatName("pam", "jim"))
+}
+
+func TestDirectChatPeer_DirectChatName_ReturnsOtherParticipant(t *testing.T) {
+	chatName := DirectChatName("jim", "pam")
+
+	assert.Equal(t, "pam", DirectChatPeer(chatName, "jim"))
+	assert.Equal(t, "jim", DirectChatPeer(chatName, "pam"))
+}
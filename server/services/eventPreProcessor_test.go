@@ -1,3 +0,0 @@
-package services
-
-// TODO
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/config"
+	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/shkotk/gochat/server/models"
+	"github.com/shkotk/gochat/server/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+// Period for which processors applied to a chat are cached. Chat configuration
+// changes made through other server instances take effect after it passes.
+const chatProcessorsTTL = 10 * time.Second
+
+// Returned when event is well-formed but its producer is not allowed to produce it.
+// Its text is reported back to the producer.
+type eventRejectedError struct {
+	text string
+}
+
+func (e eventRejectedError) Error() string { return e.text }
+
+// Processors applied to a chat and when they were resolved.
+type chatProcessors struct {
+	processors []interfaces.EventProcessor
+	loaded     time.Time
+}
+
+// Pre-processes events by passing them through registered processors in order.
+// Required processors apply to all chats, optional ones apply to chats which
+// enabled them or, unless chat is configured, which are enabled by default.
+type EventProcessorChain struct {
+	processors []interfaces.EventProcessor
+	defaults   map[string]bool
+
+	chats     map[string]chatProcessors
+	chatsLock sync.Mutex
+
+	chatRepository *repositories.ChatRepository
+	logger         *logrus.Logger
+
+	now func() time.Time
+}
+
+func NewEventProcessorChain(
+	cfg config.Config,
+	logger *logrus.Logger,
+	processors []interfaces.EventProcessor,
+	chatRepository *repositories.ChatRepository,
+) *EventProcessorChain {
+	c := &EventProcessorChain{
+		processors:     processors,
+		defaults:       make(map[string]bool),
+		chats:          make(map[string]chatProcessors),
+		chatRepository: chatRepository,
+		logger:         logger,
+		now:            time.Now,
+	}
+
+	for _, name := range cfg.Chat.DefaultProcessors {
+		if !c.IsOptional(name) {
+			logger.Fatalf("Default event processor '%s' is not an optional processor", name)
+		}
+		c.defaults[name] = true
+	}
+
+	return c
+}
+
+func (c *EventProcessorChain) PreProcess(event any, producer interfaces.Client) ([]any, error) {
+	chatName := ""
+	if event, ok := event.(events.Routed); ok {
+		chatName = event.GetChat()
+	}
+
+	processors, err := c.forChat(chatName)
+	if err != nil {
+		return nil, err
+	}
+
+	processed := []any{event}
+	for _, processor := range processors {
+		var next []any
+		for _, event := range processed {
+			produced, err := processor.Process(event, producer)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, produced...)
+		}
+		processed = next
+	}
+
+	return processed, nil
+}
+
+// Gets names of processors chats can enable or disable, in order they're applied.
+func (c *EventProcessorChain) Optional() []string {
+	names := []string{}
+	for _, processor := range c.processors {
+		if !processor.Required() {
+			names = append(names, processor.Name())
+		}
+	}
+
+	return names
+}
+
+// Checks whether processor with provided name exists and can be enabled or disabled.
+func (c *EventProcessorChain) IsOptional(name string) bool {
+	for _, processor := range c.processors {
+		if processor.Name() == name {
+			return !processor.Required()
+		}
+	}
+
+	return false
+}
+
+// Gets names of optional processors applied to the chat.
+func (c *EventProcessorChain) Enabled(chat models.Chat) []string {
+	names := []string{}
+	for _, processor := range c.applied(chat) {
+		if !processor.Required() {
+			names = append(names, processor.Name())
+		}
+	}
+
+	return names
+}
+
+// Stores names of optional processors applied to the chat, nil resets chat
+// to default processors.
+func (c *EventProcessorChain) Configure(ctx context.Context, chatName string, names []string) error {
+	if err := c.chatRepository.SetProcessors(ctx, chatName, names); err != nil {
+		return err
+	}
+
+	c.chatsLock.Lock()
+	delete(c.chats, chatName)
+	c.chatsLock.Unlock()
+
+	return nil
+}
+
+// Gets processors applied to the chat, loading chat configuration if it's not cached.
+func (c *EventProcessorChain) forChat(chatName string) ([]interfaces.EventProcessor, error) {
+	now := c.now()
+
+	c.chatsLock.Lock()
+	cached, ok := c.chats[chatName]
+	c.chatsLock.Unlock()
+	if ok && now.Sub(cached.loaded) < chatProcessorsTTL {
+		return cached.processors, nil
+	}
+
+	chat := models.Chat{Name: chatName}
+	if len(c.Optional()) > 0 {
+		stored, err := c.chatRepository.Get(context.Background(), chatName)
+		if err != nil {
+			return nil, err
+		}
+		if stored != nil {
+			chat = *stored
+		}
+	}
+
+	processors := c.applied(chat)
+
+	c.chatsLock.Lock()
+	c.chats[chatName] = chatProcessors{processors, now}
+	c.chatsLock.Unlock()
+
+	return processors, nil
+}
+
+// Selects processors applied to the chat according to its configuration.
+func (c *EventProcessorChain) applied(chat models.Chat) []interfaces.EventProcessor {
+	enabled := c.defaults
+	if chat.Processors != nil {
+		enabled = make(map[string]bool, len(chat.Processors))
+		for _, name := range chat.Processors {
+			enabled[name] = true
+		}
+	}
+
+	processors := []interfaces.EventProcessor{}
+	for _, processor := range c.processors {
+		if processor.Required() || enabled[processor.Name()] {
+			processors = append(processors, processor)
+		}
+	}
+
+	return processors
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/config"
+	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/shkotk/gochat/server/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Processor which records events it gets and processes them with provided function.
+type fakeProcessor struct {
+	name     string
+	required bool
+	process  func(event any) ([]any, error)
+	got      []any
+}
+
+func (p *fakeProcessor) Name() string { return p.name }
+
+func (p *fakeProcessor) Required() bool { return p.required }
+
+func (p *fakeProcessor) Process(event any, producer interfaces.Client) ([]any, error) {
+	p.got = append(p.got, event)
+	if p.process == nil {
+		return []any{event}, nil
+	}
+	return p.process(event)
+}
+
+func newTestProcessorChain(
+	defaults []string, processors ...interfaces.EventProcessor,
+) *EventProcessorChain {
+	return NewEventProcessorChain(
+		config.Config{Chat: config.ChatConfig{DefaultProcessors: defaults}},
+		logrus.StandardLogger(), processors, nil)
+}
+
+// Caches chat configuration so that chain doesn't load it from the database.
+func configureTestChat(chain *EventProcessorChain, chat models.Chat) {
+	chain.chats[chat.Name] = chatProcessors{chain.applied(chat), chain.now()}
+}
+
+func TestEventProcessorChain_PreProcess_RequiredProcessors_AppliesInOrder(t *testing.T) {
+	first := &fakeProcessor{name: "first", required: true, process: func(event any) ([]any, error) {
+		event.(*events.NewMessage).Text += " first"
+		return []any{event}, nil
+	}}
+	second := &fakeProcessor{name: "second", required: true, process: func(event any) ([]any, error) {
+		event.(*events.NewMessage).Text += " second"
+		return []any{event}, nil
+	}}
+	event := &events.NewMessage{Chat: "general", Text: "hi"}
+
+	processed, err := newTestProcessorChain(nil, first, second).PreProcess(event, newFakeClient("jim"))
+
+	require.Nil(t, err)
+	assert.Equal(t, []any{event}, processed)
+	assert.Equal(t, "hi first second", event.Text)
+}
+
+func TestEventProcessorChain_PreProcess_ProcessorRejectsEvent_StopsChain(t *testing.T) {
+	rejected := errors.New("rejected")
+	first := &fakeProcessor{name: "first", required: true, process: func(event any) ([]any, error) {
+		return nil, rejected
+	}}
+	second := &fakeProcessor{name: "second", required: true}
+
+	processed, err := newTestProcessorChain(nil, first, second).PreProcess(
+		&events.NewMessage{Chat: "general"}, newFakeClient("jim"))
+
+	assert.ErrorIs(t, err, rejected)
+	assert.Nil(t, processed)
+	assert.Empty(t, second.got)
+}
+
+func TestEventProcessorChain_PreProcess_ProcessorFansOut_PassesAllEventsFurther(t *testing.T) {
+	notice := &events.SystemMessage{Chat: "general", Text: "notice"}
+	first := &fakeProcessor{name: "first", required: true, process: func(event any) ([]any, error) {
+		return []any{event, notice}, nil
+	}}
+	second := &fakeProcessor{name: "second", required: true}
+	event := &events.NewMessage{Chat: "general"}
+
+	processed, err := newTestProcessorChain(nil, first, second).PreProcess(event, newFakeClient("jim"))
+
+	require.Nil(t, err)
+	assert.Equal(t, []any{event, notice}, processed)
+	assert.Equal(t, []any{event, notice}, second.got)
+}
+
+func TestEventProcessorChain_PreProcess_OptionalProcessors_AppliesEnabledForChat(t *testing.T) {
+	required := &fakeProcessor{name: "required", required: true}
+	byDefault := &fakeProcessor{name: "default"}
+	other := &fakeProcessor{name: "other"}
+	chain := newTestProcessorChain([]string{"default"}, required, byDefault, other)
+	configureTestChat(chain, models.Chat{Name: "defaults"})
+	configureTestChat(chain, models.Chat{Name: "configured", Processors: []string{"other"}})
+	configureTestChat(chain, models.Chat{Name: "none", Processors: []string{}})
+
+	for _, chatName := range []string{"defaults", "configured", "none"} {
+		_, err := chain.PreProcess(&events.NewMessage{Chat: chatName}, newFakeClient("jim"))
+		require.Nil(t, err)
+	}
+
+	assert.Len(t, required.got, 3)
+	assert.Equal(t, []any{&events.NewMessage{Chat: "defaults"}}, byDefault.got)
+	assert.Equal(t, []any{&events.NewMessage{Chat: "configured"}}, other.got)
+}
+
+func TestEventProcessorChain_Enabled_ChatConfiguration_ReturnsOptionalProcessorNames(t *testing.T) {
+	chain := newTestProcessorChain([]string{"default"},
+		&fakeProcessor{name: "required", required: true},
+		&fakeProcessor{name: "default"},
+		&fakeProcessor{name: "other"})
+
+	assert.Equal(t, []string{"default", "other"}, chain.Optional())
+	assert.Equal(t, []string{"default"}, chain.Enabled(models.Chat{}))
+	assert.Equal(t, []string{"other"}, chain.Enabled(models.Chat{Processors: []string{"other"}}))
+	assert.True(t, chain.IsOptional("other"))
+	assert.False(t, chain.IsOptional("required"))
+	assert.False(t, chain.IsOptional("missing"))
+}
+
+func TestEventProcessorChain_PreProcess_CachedConfigurationExpired_ReloadsIt(t *testing.T) {
+	clock := &fakeClock{time.Now()}
+	chain := newTestProcessorChain(nil, &fakeProcessor{name: "required", required: true})
+	chain.now = clock.now
+	configureTestChat(chain, models.Chat{Name: "general"})
+
+	clock.advance(chatProcessorsTTL)
+
+	// without optional processors configuration is not loaded from the database
+	_, err := chain.PreProcess(&events.NewMessage{Chat: "general"}, newFakeClient("jim"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, clock.time, chain.chats["general"].loaded)
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/shkotk/gochat/server/models"
+	"github.com/shkotk/gochat/server/repositories"
+)
+
+// Matches emoji shortcodes without colons, e.g. "thumbsup" or "+1".
+var emojiRegexp = regexp.MustCompile("^[a-z0-9_+-]{1,32}$")
+
+// Filters expected incoming event types, checks that producer is allowed
+// to produce them and stamps them with producer and time.
+type EventValidator struct {
+	messageRepository    *repositories.MessageRepository
+	chatMemberRepository *repositories.ChatMemberRepository
+}
+
+func NewEventValidator(
+	messageRepository *repositories.MessageRepository,
+	chatMemberRepository *repositories.ChatMemberRepository,
+) *EventValidator {
+	return &EventValidator{messageRepository, chatMemberRepository}
+}
+
+func (v *EventValidator) Name() string { return "validation" }
+
+func (v *EventValidator) Required() bool { return true }
+
+func (v *EventValidator) Process(event any, producer interfaces.Client) ([]any, error) {
+	switch event := event.(type) {
+	case *events.NewMessage:
+		if event.ReplyTo != "" {
+			if _, err := v.getMessage(event.Chat, event.ReplyTo); err != nil {
+				return nil, err
+			}
+		}
+		// mentions are filled in by MentionParser if chat has it enabled,
+		// action messages are posted with /me command
+		event.Mentions = nil
+		event.Action = false
+		// only replayed messages can be marked as changed or have reactions
+		event.Edited = false
+		event.Deleted = false
+		event.Reactions = nil
+	case *events.Typing:
+	case *events.EditMessage:
+		if event.Text == "" {
+			return nil, eventRejectedError{"message text can't be empty"}
+		}
+		if err := v.authorizeChange(event.Chat, event.ID, producer.ID()); err != nil {
+			return nil, err
+		}
+	case *events.DeleteMessage:
+		if err := v.authorizeChange(event.Chat, event.ID, producer.ID()); err != nil {
+			return nil, err
+		}
+	case *events.AddReaction:
+		if err := v.validateReaction(event.Chat, event.MessageID, event.Emoji); err != nil {
+			return nil, err
+		}
+	case *events.RemoveReaction:
+		if err := v.validateReaction(event.Chat, event.MessageID, event.Emoji); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("chat: got event of unexpected type %T from client '%s'",
+			event, producer.ID())
+	}
+
+	if event, ok := event.(events.Produced); ok {
+		event.SetProducer(producer.ID())
+	}
+	if event, ok := event.(events.Timed); ok {
+		event.SetTime(time.Now())
+	}
+
+	return []any{event}, nil
+}
+
+// Checks that message exists and user is either its producer or a chat moderator.
+func (v *EventValidator) authorizeChange(chatName, messageID, username string) error {
+	message, err := v.getMessage(chatName, messageID)
+	if err != nil {
+		return err
+	}
+	if message.Producer == username {
+		return nil
+	}
+
+	role, err := v.chatMemberRepository.GetRole(context.Background(), chatName, username)
+	if err != nil {
+		return err
+	}
+	if !role.CanModerate() {
+		return eventRejectedError{"only message author or chat moderator can change it"}
+	}
+
+	return nil
+}
+
+// Checks that emoji is a valid shortcode and message exists.
+func (v *EventValidator) validateReaction(chatName, messageID, emoji string) error {
+	if !emojiRegexp.MatchString(emoji) {
+		return eventRejectedError{fmt.Sprintf("'%s' is not a valid emoji", emoji)}
+	}
+
+	_, err := v.getMessage(chatName, messageID)
+	return err
+}
+
+// Gets message of the chat, returns error if message doesn't exist or was deleted.
+func (v *EventValidator) getMessage(chatName, messageID string) (*models.Message, error) {
+	message, err := v.messageRepository.Get(context.Background(), chatName, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message == nil || message.Deleted {
+		return nil, eventRejectedError{"message not found"}
+	}
+
+	return message, nil
+}
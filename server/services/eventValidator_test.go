@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventValidator_Process_Typing_SetsProducerAndTime(t *testing.T) {
+	event := &events.Typing{}
+
+	processed, err := NewEventValidator(nil, nil).Process(event, newFakeClient("jim"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []any{event}, processed)
+	assert.Equal(t, "jim", event.Producer)
+	assert.False(t, event.Time.IsZero())
+}
+
+func TestEventValidator_Process_NewMessageWithServerFields_ResetsThem(t *testing.T) {
+	tests := []struct {
+		label string
+		event *events.NewMessage
+	}{
+		{"mentions", &events.NewMessage{Text: "hi", Mentions: []string{"jim"}}},
+		{"action", &events.NewMessage{Text: "hi", Action: true}},
+		{"edited", &events.NewMessage{Text: "hi", Edited: true}},
+		{"deleted", &events.NewMessage{Text: "hi", Deleted: true}},
+		{"reactions", &events.NewMessage{
+			Text: "hi", Reactions: []events.ReactionCount{{Emoji: "tada", Count: 100}}}},
+	}
+
+	for _, test := range tests {
+		_, err := NewEventValidator(nil, nil).Process(test.event, newFakeClient("pam"))
+
+		assert.Nil(t, err)
+		assert.Equal(t, &events.NewMessage{
+			Producer: "pam", Time: test.event.Time, Text: "hi",
+		}, test.event, "%s wasn't reset", test.label)
+	}
+}
+
+func TestEventValidator_Process_UnexpectedEvent_ReturnsError(t *testing.T) {
+	_, err := NewEventValidator(nil, nil).Process(&events.SystemMessage{}, newFakeClient("jim"))
+
+	assert.NotNil(t, err)
+}
+
+func TestEventValidator_Process_EditMessageWithoutText_ReturnsError(t *testing.T) {
+	_, err := NewEventValidator(nil, nil).Process(&events.EditMessage{ID: "1"}, newFakeClient("jim"))
+
+	assert.ErrorIs(t, err, eventRejectedError{"message text can't be empty"})
+}
+
+func TestEventValidator_Process_ReactionWithInvalidEmoji_ReturnsError(t *testing.T) {
+	tests := []any{
+		&events.AddReaction{MessageID: "1", Emoji: ""},
+		&events.AddReaction{MessageID: "1", Emoji: ":tada:"},
+		&events.RemoveReaction{MessageID: "1", Emoji: "thumbs up"},
+	}
+
+	for _, event := range tests {
+		_, err := NewEventValidator(nil, nil).Process(event, newFakeClient("jim"))
+
+		assert.ErrorAs(t, err, &eventRejectedError{}, "%+v was not rejected", event)
+	}
+}
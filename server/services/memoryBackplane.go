@@ -0,0 +1,26 @@
+package services
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Backplane delivering messages within a single process.
+// Suitable for running a single server instance.
+type MemoryBackplane struct {
+	subscribers *backplaneSubscribers
+}
+
+func NewMemoryBackplane(logger *logrus.Logger) *MemoryBackplane {
+	return &MemoryBackplane{newBackplaneSubscribers(logger)}
+}
+
+func (b *MemoryBackplane) Publish(_ context.Context, topic string, payload []byte) error {
+	b.subscribers.dispatch(topic, payload)
+	return nil
+}
+
+func (b *MemoryBackplane) Subscribe(ctx context.Context, topic string) <-chan []byte {
+	return b.subscribers.subscribe(ctx, topic)
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/shkotk/gochat/server/repositories"
+)
+
+// Matches @username mentions not preceded by a word character, so that
+// e-mail addresses aren't taken for mentions.
+var mentionRegexp = regexp.MustCompile(`(?:^|[^\w@])@([a-zA-Z0-9]+(?:[._-][a-zA-Z0-9]+)*)`)
+
+// Fills in mentions of users who can be mentioned in the chat in new messages.
+type MentionParser struct {
+	userRepository *repositories.UserRepository
+}
+
+func NewMentionParser(userRepository *repositories.UserRepository) *MentionParser {
+	return &MentionParser{userRepository}
+}
+
+func (p *MentionParser) Name() string { return "mentions" }
+
+func (p *MentionParser) Required() bool { return false }
+
+func (p *MentionParser) Process(event any, producer interfaces.Client) ([]any, error) {
+	message, ok := event.(*events.NewMessage)
+	if !ok {
+		return []any{event}, nil
+	}
+
+	message.Mentions = nil
+	if mentions := findMentions(message.Text); len(mentions) > 0 {
+		mentionable, err := p.userRepository.FilterMentionable(
+			context.Background(), message.Chat, mentions)
+		if err != nil {
+			return nil, err
+		}
+		message.Mentions = mentionable
+	}
+
+	return []any{event}, nil
+}
+
+// Gets distinct usernames mentioned in text in order of their first mention.
+func findMentions(text string) []string {
+	usernames := []string{}
+	seen := make(map[string]bool)
+	for _, match := range mentionRegexp.FindAllStringSubmatch(text, -1) {
+		if username := match[1]; !seen[username] {
+			seen[username] = true
+			usernames = append(usernames, username)
+		}
+	}
+
+	return usernames
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMentionParser_Process_MessageWithoutMentions_ClearsMentions(t *testing.T) {
+	event := &events.NewMessage{Text: "hi all", Mentions: []string{"jim"}}
+
+	processed, err := NewMentionParser(nil).Process(event, newFakeClient("pam"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []any{event}, processed)
+	assert.Nil(t, event.Mentions)
+}
+
+func TestFindMentions_TextWithMentions_ReturnsDistinctUsernames(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"no mentions", []string{}},
+		{"@jim hi", []string{"jim"}},
+		{"hi @jim and @pam.beesly, @jim", []string{"jim", "pam.beesly"}},
+		{"(@dwight) mail jim@example.com", []string{"dwight"}},
+		{"@@jim @", []string{}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, findMentions(test.text), "wrong mentions in '%s'", test.text)
+	}
+}
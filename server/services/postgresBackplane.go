@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shkotk/gochat/server/config"
+	"github.com/shkotk/gochat/server/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// Postgres notification channel shared by all topics.
+	postgresBackplaneChannel = "gochat_backplane"
+
+	// Delay before listening connection is re-established after failure.
+	postgresBackplaneReconnectDelay = 5 * time.Second
+
+	// Notification payload must be shorter than 8000 bytes in default Postgres
+	// configuration, larger payloads are stored in the database instead.
+	postgresNotificationSizeLimit = 8000
+
+	// Period after which stored payloads are removed, as listeners fetch them
+	// as soon as they're notified.
+	postgresStoredPayloadTTL = time.Minute
+)
+
+// Backplane delivering messages between server instances using Postgres LISTEN/NOTIFY.
+// Messages published while listening connection is being re-established are lost.
+type PostgresBackplane struct {
+	subscribers *backplaneSubscribers
+
+	db     *gorm.DB
+	logger *logrus.Logger
+}
+
+var errPostgresNotificationTooLarge = errors.New("notification payload is too large")
+
+type postgresNotification struct {
+	Topic   string `json:"topic"`
+	Payload string `json:"payload,omitempty"`
+
+	// Set instead of payload if it was too large and was stored in the database.
+	PayloadID string `json:"payloadId,omitempty"`
+}
+
+// Creates PostgresBackplane and starts listening for notifications.
+func NewPostgresBackplane(cfg config.Config, db *gorm.DB, logger *logrus.Logger) *PostgresBackplane {
+	b := &PostgresBackplane{
+		subscribers: newBackplaneSubscribers(logger),
+		db:          db,
+		logger:      logger,
+	}
+
+	go b.listen(context.Background(), cfg.PGConnString)
+
+	return b
+}
+
+func (b *PostgresBackplane) Publish(ctx context.Context, topic string, payload []byte) error {
+	notification, err := encodePostgresNotification(
+		postgresNotification{Topic: topic, Payload: string(payload)})
+	if errors.Is(err, errPostgresNotificationTooLarge) {
+		notification, err = b.store(ctx, topic, payload)
+	}
+	if err != nil {
+		return err
+	}
+
+	return b.db.WithContext(ctx).
+		Exec("SELECT pg_notify(?, ?)", postgresBackplaneChannel, string(notification)).
+		Error
+}
+
+// Encodes notification, returning errPostgresNotificationTooLarge if it's too large
+// to be sent with pg_notify.
+func encodePostgresNotification(notification postgresNotification) ([]byte, error) {
+	encoded, err := json.Marshal(notification)
+	if err != nil {
+		return nil, err
+	}
+	if len(encoded) >= postgresNotificationSizeLimit {
+		return nil, errPostgresNotificationTooLarge
+	}
+
+	return encoded, nil
+}
+
+// Stores payload too large to fit into notification, removing expired ones.
+// Returns notification carrying ID of the stored payload.
+func (b *PostgresBackplane) store(ctx context.Context, topic string, payload []byte) ([]byte, error) {
+	stored := models.BackplanePayload{ID: uuid.NewString(), Payload: payload, CreatedAt: time.Now()}
+	err := b.db.WithContext(ctx).
+		Where("created_at < ?", stored.CreatedAt.Add(-postgresStoredPayloadTTL)).
+		Delete(&models.BackplanePayload{}).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	if err = b.db.WithContext(ctx).Create(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	return encodePostgresNotification(postgresNotification{Topic: topic, PayloadID: stored.ID})
+}
+
+func (b *PostgresBackplane) Subscribe(ctx context.Context, topic string) <-chan []byte {
+	return b.subscribers.subscribe(ctx, topic)
+}
+
+// Listens for notifications until context is done, reconnecting on failures.
+func (b *PostgresBackplane) listen(ctx context.Context, connString string) {
+	for {
+		err := b.listenConnection(ctx, connString)
+		if ctx.Err() != nil {
+			return
+		}
+		b.logger.WithError(err).Error("backplane: listening failed, reconnecting")
+
+		select {
+		case <-time.After(postgresBackplaneReconnectDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *PostgresBackplane) listenConnection(ctx context.Context, connString string) error {
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err = conn.Exec(ctx, "LISTEN "+postgresBackplaneChannel); err != nil {
+		return err
+	}
+
+	for {
+		received, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var notification postgresNotification
+		if err = json.Unmarshal([]byte(received.Payload), &notification); err != nil {
+			b.logger.WithError(err).Warnf(
+				"backplane: failed to parse notification '%s'", received.Payload)
+			continue
+		}
+
+		payload := []byte(notification.Payload)
+		if notification.PayloadID != "" {
+			if payload, err = b.load(ctx, notification.PayloadID); err != nil {
+				b.logger.WithError(err).Warnf(
+					"backplane: failed to load stored payload '%s'", notification.PayloadID)
+				continue
+			}
+		}
+
+		b.subscribers.dispatch(notification.Topic, payload)
+	}
+}
+
+// Loads payload stored because it didn't fit into notification.
+func (b *PostgresBackplane) load(ctx context.Context, id string) ([]byte, error) {
+	var stored models.BackplanePayload
+	if err := b.db.WithContext(ctx).Take(&stored, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	return stored.Payload, nil
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodePostgresNotification_SerializedEventPayload_RoundTrips(t *testing.T) {
+	payload := `Mentioned|{"Chat":"general","Text":"\"hi\" @jim"}`
+
+	encoded, err := encodePostgresNotification(postgresNotification{Topic: "user/jim", Payload: payload})
+
+	require.Nil(t, err)
+	var decoded postgresNotification
+	require.Nil(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, postgresNotification{Topic: "user/jim", Payload: payload}, decoded)
+}
+
+func TestEncodePostgresNotification_PayloadOverSizeLimit_ReturnsError(t *testing.T) {
+	payload := `{"members":["` + strings.Repeat("a", postgresNotificationSizeLimit) + `"]}`
+
+	_, err := encodePostgresNotification(postgresNotification{Topic: "general", Payload: payload})
+
+	assert.ErrorIs(t, err, errPostgresNotificationTooLarge)
+}
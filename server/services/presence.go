@@ -0,0 +1,74 @@
+package services
+
+import "sort"
+
+// Tracks users present in a chat across server instances.
+type presence struct {
+	// Server instances each user is connected to.
+	instances map[string]map[string]struct{}
+}
+
+func newPresence() *presence {
+	return &presence{make(map[string]map[string]struct{})}
+}
+
+// Marks user as connected to the instance.
+// Returns true if user wasn't connected to any instance before.
+func (p *presence) add(username, instanceID string) bool {
+	instances, ok := p.instances[username]
+	if !ok {
+		instances = make(map[string]struct{})
+		p.instances[username] = instances
+	}
+	instances[instanceID] = struct{}{}
+
+	return !ok
+}
+
+// Checks whether user is connected to any instance.
+func (p *presence) has(username string) bool {
+	_, ok := p.instances[username]
+	return ok
+}
+
+// Marks user as disconnected from the instance.
+// Returns true if user isn't connected to any instance anymore.
+func (p *presence) remove(username, instanceID string) bool {
+	instances, ok := p.instances[username]
+	if !ok {
+		return false
+	}
+
+	delete(instances, instanceID)
+	if len(instances) > 0 {
+		return false
+	}
+
+	delete(p.instances, username)
+	return true
+}
+
+// Marks all users as disconnected from the instance.
+// Returns users which aren't connected to any instance anymore.
+func (p *presence) removeInstance(instanceID string) []string {
+	left := []string{}
+	for username := range p.instances {
+		if p.remove(username, instanceID) {
+			left = append(left, username)
+		}
+	}
+	sort.Strings(left)
+
+	return left
+}
+
+// Gets sorted usernames of present users.
+func (p *presence) usernames() []string {
+	usernames := make([]string, 0, len(p.instances))
+	for username := range p.instances {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	return usernames
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/config"
+	"github.com/shkotk/gochat/server/interfaces"
+)
+
+// Returned by EventRateLimiter when user keeps flooding after being muted
+// and should be disconnected.
+var errFlooding = errors.New("user keeps exceeding event rate limit")
+
+// Period after which state of a key which wasn't limited is forgotten.
+const rateLimitStateTTL = 10 * time.Minute
+
+// Token bucket which allows burst events at once and one more event per interval.
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Token buckets sharing the same limit, one per key. Buckets which weren't
+// used for rateLimitStateTTL are removed.
+type tokenBuckets struct {
+	limit     config.Limit
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+}
+
+func newTokenBuckets(limit config.Limit) *tokenBuckets {
+	return &tokenBuckets{limit: limit, buckets: make(map[string]*tokenBucket)}
+}
+
+// Takes token from the bucket of the key if there is one.
+// Returns false if event should be rejected.
+func (b *tokenBuckets) take(key string, now time.Time) bool {
+	b.prune(now)
+
+	bucket, ok := b.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(b.limit.Burst), updated: now}
+		b.buckets[key] = bucket
+	}
+
+	if b.limit.Interval > 0 {
+		refill := float64(now.Sub(bucket.updated)) / float64(b.limit.Interval)
+		bucket.tokens = math.Min(float64(b.limit.Burst), bucket.tokens+refill)
+	}
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+
+	return true
+}
+
+func (b *tokenBuckets) prune(now time.Time) {
+	if now.Sub(b.lastPrune) < rateLimitStateTTL {
+		return
+	}
+
+	b.lastPrune = now
+	for key, bucket := range b.buckets {
+		if now.Sub(bucket.updated) >= rateLimitStateTTL {
+			delete(b.buckets, key)
+		}
+	}
+}
+
+// Escalation state of a user exceeding event rate limit.
+type floodState struct {
+	violations int
+	mutes      int
+	mutedUntil time.Time
+	updated    time.Time
+}
+
+// Limits rate of events users send to chats. User exceeding the limit gets their
+// events rejected, then gets muted for a while, then disconnected.
+type EventRateLimiter struct {
+	cfg config.RateLimitConfig
+
+	users     *tokenBuckets
+	chats     *tokenBuckets
+	floods    map[string]*floodState
+	lastPrune time.Time
+	lock      sync.Mutex
+
+	now func() time.Time
+}
+
+func NewEventRateLimiter(cfg config.Config) *EventRateLimiter {
+	return &EventRateLimiter{
+		cfg:    cfg.RateLimit,
+		users:  newTokenBuckets(cfg.RateLimit.UserEvents),
+		chats:  newTokenBuckets(cfg.RateLimit.ChatEvents),
+		floods: make(map[string]*floodState),
+		now:    time.Now,
+	}
+}
+
+func (l *EventRateLimiter) Name() string { return "rate_limit" }
+
+func (l *EventRateLimiter) Required() bool { return true }
+
+func (l *EventRateLimiter) Process(event any, producer interfaces.Client) ([]any, error) {
+	// typing notifications are cheap and throttled by clients, so only limit the rest
+	if _, ok := event.(*events.Typing); ok {
+		return []any{event}, nil
+	}
+
+	chatName := ""
+	if event, ok := event.(events.Routed); ok {
+		chatName = event.GetChat()
+	}
+	if err := l.Allow(producer.ID(), chatName); err != nil {
+		return nil, err
+	}
+
+	return []any{event}, nil
+}
+
+// Checks whether user can send another event to the chat. Returns eventRejectedError
+// if event should be dropped or errFlooding if user should be disconnected.
+func (l *EventRateLimiter) Allow(username, chatName string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	flood := l.floods[username]
+	if flood != nil && now.Before(flood.mutedUntil) {
+		return eventRejectedError{fmt.Sprintf(
+			"you are muted for flooding, wait %v", flood.mutedUntil.Sub(now).Round(time.Second))}
+	}
+
+	if !l.users.take(username, now) {
+		if flood == nil {
+			flood = &floodState{}
+			l.floods[username] = flood
+		}
+		return l.escalate(flood, now)
+	}
+
+	if !l.chats.take(chatName, now) {
+		return eventRejectedError{"chat is receiving too many events, try again later"}
+	}
+
+	return nil
+}
+
+// Counts limit violation, muting or disconnecting user once they've made too many.
+func (l *EventRateLimiter) escalate(flood *floodState, now time.Time) error {
+	flood.updated = now
+	flood.violations++
+	if flood.violations < l.cfg.MuteAfter {
+		return eventRejectedError{"you are sending events too fast, slow down"}
+	}
+
+	flood.violations = 0
+	flood.mutes++
+	flood.mutedUntil = now.Add(l.cfg.MuteDuration)
+	// user stays muted and is disconnected on the next mute as well, so the state
+	// survives rejoining chat until it's forgotten after rateLimitStateTTL
+	if flood.mutes > l.cfg.DisconnectAfter {
+		return errFlooding
+	}
+
+	return eventRejectedError{fmt.Sprintf(
+		"you are muted for flooding, wait %v", l.cfg.MuteDuration)}
+}
+
+func (l *EventRateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < rateLimitStateTTL {
+		return
+	}
+
+	l.lastPrune = now
+	for username, flood := range l.floods {
+		if now.Sub(flood.updated) >= rateLimitStateTTL && !now.Before(flood.mutedUntil) {
+			delete(l.floods, username)
+		}
+	}
+}
+
+// Limits rate of REST requests by key, e.g. client IP address and endpoint.
+type RequestRateLimiter struct {
+	buckets *tokenBuckets
+	lock    sync.Mutex
+
+	now func() time.Time
+}
+
+func NewRequestRateLimiter(cfg config.Config) *RequestRateLimiter {
+	return &RequestRateLimiter{
+		buckets: newTokenBuckets(cfg.RateLimit.Requests),
+		now:     time.Now,
+	}
+}
+
+// Checks whether another request with provided key can be served.
+func (l *RequestRateLimiter) Allow(key string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.buckets.take(key, l.now())
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shkotk/gochat/server/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// Creates limiter generous enough not to interfere with tests of other services.
+func newTestEventRateLimiter() *EventRateLimiter {
+	return NewEventRateLimiter(config.Config{RateLimit: config.RateLimitConfig{
+		UserEvents: config.Limit{Burst: 1000, Interval: time.Millisecond},
+		ChatEvents: config.Limit{Burst: 1000, Interval: time.Millisecond},
+	}})
+}
+
+// Clock which only moves when told to.
+type fakeClock struct {
+	time time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.time }
+
+func (c *fakeClock) advance(d time.Duration) { c.time = c.time.Add(d) }
+
+func newFakeClockEventRateLimiter(cfg config.RateLimitConfig) (*EventRateLimiter, *fakeClock) {
+	clock := &fakeClock{time.Now()}
+	limiter := NewEventRateLimiter(config.Config{RateLimit: cfg})
+	limiter.now = clock.now
+	return limiter, clock
+}
+
+func TestEventRateLimiter_Allow_BurstExceeded_RejectsUntilRefilled(t *testing.T) {
+	limiter, clock := newFakeClockEventRateLimiter(config.RateLimitConfig{
+		UserEvents: config.Limit{Burst: 2, Interval: time.Second},
+		ChatEvents: config.Limit{Burst: 100, Interval: time.Millisecond},
+		MuteAfter:  10,
+	})
+
+	assert.Nil(t, limiter.Allow("jim", "general"))
+	assert.Nil(t, limiter.Allow("jim", "general"))
+	assert.ErrorAs(t, limiter.Allow("jim", "general"), &eventRejectedError{})
+	assert.Nil(t, limiter.Allow("pam", "general"), "other user was limited")
+
+	clock.advance(time.Second)
+
+	assert.Nil(t, limiter.Allow("jim", "general"))
+	assert.ErrorAs(t, limiter.Allow("jim", "general"), &eventRejectedError{})
+}
+
+func TestEventRateLimiter_Allow_ChatBurstExceeded_RejectsAllUsers(t *testing.T) {
+	limiter, _ := newFakeClockEventRateLimiter(config.RateLimitConfig{
+		UserEvents: config.Limit{Burst: 100, Interval: time.Millisecond},
+		ChatEvents: config.Limit{Burst: 2, Interval: time.Second},
+	})
+
+	assert.Nil(t, limiter.Allow("jim", "general"))
+	assert.Nil(t, limiter.Allow("pam", "general"))
+	assert.ErrorAs(t, limiter.Allow("dwight", "general"), &eventRejectedError{})
+	assert.Nil(t, limiter.Allow("dwight", "random"), "other chat was limited")
+}
+
+func TestEventRateLimiter_Allow_RepeatedViolations_MutesThenDisconnects(t *testing.T) {
+	limiter, clock := newFakeClockEventRateLimiter(config.RateLimitConfig{
+		UserEvents:      config.Limit{Burst: 1, Interval: time.Second},
+		ChatEvents:      config.Limit{Burst: 100, Interval: time.Millisecond},
+		MuteAfter:       2,
+		MuteDuration:    time.Minute,
+		DisconnectAfter: 1,
+	})
+
+	assert.Nil(t, limiter.Allow("jim", "general"))
+	assert.ErrorAs(t, limiter.Allow("jim", "general"), &eventRejectedError{})
+	assert.ErrorIs(t, limiter.Allow("jim", "general"),
+		eventRejectedError{"you are muted for flooding, wait 1m0s"})
+
+	clock.advance(30 * time.Second)
+	assert.ErrorIs(t, limiter.Allow("jim", "general"),
+		eventRejectedError{"you are muted for flooding, wait 30s"}, "user was unmuted too early")
+
+	clock.advance(30 * time.Second)
+	assert.Nil(t, limiter.Allow("jim", "general"))
+	assert.ErrorAs(t, limiter.Allow("jim", "general"), &eventRejectedError{})
+	assert.ErrorIs(t, limiter.Allow("jim", "general"), errFlooding)
+}
+
+func TestEventRateLimiter_Allow_DisconnectedUserRejoins_KeepsEscalation(t *testing.T) {
+	limiter, clock := newFakeClockEventRateLimiter(config.RateLimitConfig{
+		UserEvents:      config.Limit{Burst: 1, Interval: time.Second},
+		ChatEvents:      config.Limit{Burst: 100, Interval: time.Millisecond},
+		MuteAfter:       1,
+		MuteDuration:    time.Minute,
+		DisconnectAfter: 0,
+	})
+	assert.Nil(t, limiter.Allow("jim", "general"))
+	assert.ErrorIs(t, limiter.Allow("jim", "general"), errFlooding)
+
+	assert.ErrorIs(t, limiter.Allow("jim", "general"),
+		eventRejectedError{"you are muted for flooding, wait 1m0s"}, "user wasn't muted after disconnect")
+
+	clock.advance(time.Minute)
+	assert.Nil(t, limiter.Allow("jim", "general"))
+	assert.ErrorIs(t, limiter.Allow("jim", "general"), errFlooding, "escalation was reset")
+}
+
+func TestRequestRateLimiter_Allow_BurstExceeded_RejectsKey(t *testing.T) {
+	clock := &fakeClock{time.Now()}
+	limiter := NewRequestRateLimiter(config.Config{RateLimit: config.RateLimitConfig{
+		Requests: config.Limit{Burst: 1, Interval: time.Minute},
+	}})
+	limiter.now = clock.now
+
+	assert.True(t, limiter.Allow("/token/get 10.0.0.1"))
+	assert.False(t, limiter.Allow("/token/get 10.0.0.1"))
+	assert.True(t, limiter.Allow("/token/get 10.0.0.2"))
+	assert.True(t, limiter.Allow("/chat/create 10.0.0.1"))
+
+	clock.advance(time.Minute)
+
+	assert.True(t, limiter.Allow("/token/get 10.0.0.1"))
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/sirupsen/logrus"
+)
+
+// Multiplexes several chats over a single client connection. Client subscribes to
+// and unsubscribes from chats by sending corresponding events, other incoming events
+// are routed to subscribed chats by their chat name.
+type Session struct {
+	client        interfaces.Client
+	subscriptions map[string]*subscription
+
+	chatManager interfaces.ChatManager
+	logger      *logrus.Logger
+}
+
+func NewSession(
+	client interfaces.Client,
+	chatManager interfaces.ChatManager,
+	logger *logrus.Logger,
+) *Session {
+	return &Session{
+		client:        client,
+		subscriptions: make(map[string]*subscription),
+		chatManager:   chatManager,
+		logger:        logger,
+	}
+}
+
+// Loop processing incoming client events. Returns when client is done.
+func (s *Session) Run() {
+	defer s.unsubscribeAll()
+
+	for {
+		select {
+		case event := <-s.client.In():
+			s.process(event)
+		case <-s.client.Done():
+			return
+		}
+	}
+}
+
+func (s *Session) process(event any) {
+	switch event := event.(type) {
+	case *events.Subscribe:
+		s.subscribe(event.Chat, event.LastSeq)
+	case *events.Unsubscribe:
+		s.unsubscribe(event.Chat)
+	case events.Routed:
+		s.route(event)
+	default:
+		s.sendError("", fmt.Sprintf("unexpected event of type %T", event))
+	}
+}
+
+func (s *Session) subscribe(chatName string, lastSeq uint64) {
+	if sub, ok := s.subscriptions[chatName]; ok && !sub.isClosed() {
+		s.sendError(chatName, "already subscribed to chat")
+		return
+	}
+
+	sub := newSubscription(s.client)
+	err := s.chatManager.AddClient(context.Background(), sub, chatName, lastSeq)
+	if err != nil {
+		s.logger.WithError(err).Warnf(
+			"session: failed to subscribe '%s' to chat '%s'", s.client.ID(), chatName)
+		s.sendError(chatName, err.Error())
+		return
+	}
+
+	s.subscriptions[chatName] = sub
+}
+
+func (s *Session) unsubscribe(chatName string) {
+	sub, ok := s.subscriptions[chatName]
+	if !ok {
+		s.sendError(chatName, "not subscribed to chat")
+		return
+	}
+
+	sub.Close(interfaces.CloseNormal, "")
+	delete(s.subscriptions, chatName)
+}
+
+func (s *Session) unsubscribeAll() {
+	for chatName, sub := range s.subscriptions {
+		sub.Close(interfaces.CloseNormal, "")
+		delete(s.subscriptions, chatName)
+	}
+}
+
+// Passes event to the chat it's addressed to.
+func (s *Session) route(event events.Routed) {
+	chatName := event.GetChat()
+	sub, ok := s.subscriptions[chatName]
+	if !ok || sub.isClosed() {
+		delete(s.subscriptions, chatName)
+		s.sendError(chatName, "not subscribed to chat")
+		return
+	}
+
+	select {
+	case sub.in <- event:
+	case <-sub.done:
+	case <-s.client.Done():
+	}
+}
+
+func (s *Session) sendError(chatName, text string) {
+	s.client.Send(&events.Error{
+		Chat: chatName,
+		Text: text,
+		Time: time.Now(),
+	})
+}
+
+// Client of a single chat within a session. Outgoing events are queued directly
+// to session client, closing subscription doesn't affect session client.
+type subscription struct {
+	client interfaces.Client
+
+	in        chan any
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSubscription(client interfaces.Client) *subscription {
+	return &subscription{
+		client: client,
+		in:     make(chan any),
+		done:   make(chan struct{}),
+	}
+}
+
+func (s *subscription) ID() string            { return s.client.ID() }
+func (s *subscription) In() <-chan any        { return s.in }
+func (s *subscription) Send(event any)        { s.client.Send(event) }
+func (s *subscription) Done() <-chan struct{} { return s.done }
+func (s *subscription) Close(int, string)     { s.closeOnce.Do(func() { close(s.done) }) }
+
+func (s *subscription) isClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/config"
+	"github.com/shkotk/gochat/server/interfaces"
+)
+
+// Masks configured words in message texts with asterisks.
+type WordFilter struct {
+	// Matches any of filtered words, nil if there are none.
+	regexp *regexp.Regexp
+}
+
+func NewWordFilter(cfg config.Config) *WordFilter {
+	if len(cfg.Chat.FilteredWords) == 0 {
+		return &WordFilter{}
+	}
+
+	words := make([]string, len(cfg.Chat.FilteredWords))
+	for i, word := range cfg.Chat.FilteredWords {
+		words[i] = regexp.QuoteMeta(word)
+	}
+
+	return &WordFilter{regexp.MustCompile(`(?i)\b(?:` + strings.Join(words, "|") + `)\b`)}
+}
+
+func (f *WordFilter) Name() string { return "word_filter" }
+
+func (f *WordFilter) Required() bool { return false }
+
+func (f *WordFilter) Process(event any, producer interfaces.Client) ([]any, error) {
+	switch event := event.(type) {
+	case *events.NewMessage:
+		event.Text = f.mask(event.Text)
+	case *events.EditMessage:
+		event.Text = f.mask(event.Text)
+	}
+
+	return []any{event}, nil
+}
+
+func (f *WordFilter) mask(text string) string {
+	if f.regexp == nil {
+		return text
+	}
+
+	return f.regexp.ReplaceAllStringFunc(text, func(word string) string {
+		return strings.Repeat("*", len([]rune(word)))
+	})
+}
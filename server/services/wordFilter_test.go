@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWordFilter_Process_MessagesWithFilteredWords_MasksWords(t *testing.T) {
+	filter := NewWordFilter(config.Config{Chat: config.ChatConfig{
+		FilteredWords: []string{"darn", "heck"},
+	}})
+
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"nothing to hide", "nothing to hide"},
+		{"Darn it, darn!", "**** it, ****!"},
+		{"darnest", "darnest"},
+		{"what the HECK", "what the ****"},
+	}
+
+	for _, test := range tests {
+		message := &events.NewMessage{Text: test.text}
+		edit := &events.EditMessage{Text: test.text}
+
+		_, err := filter.Process(message, newFakeClient("jim"))
+		assert.Nil(t, err)
+		_, err = filter.Process(edit, newFakeClient("jim"))
+		assert.Nil(t, err)
+
+		assert.Equal(t, test.expected, message.Text)
+		assert.Equal(t, test.expected, edit.Text)
+	}
+}
+
+func TestWordFilter_Process_NoFilteredWords_KeepsText(t *testing.T) {
+	message := &events.NewMessage{Text: "darn"}
+
+	_, err := NewWordFilter(config.Config{}).Process(message, newFakeClient("jim"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "darn", message.Text)
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/shkotk/gochat/server/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Removes records of all tables created by CreateDB.
+const truncateTablesQuery = `TRUNCATE TABLE "users", "chats", "chat_members", "chat_invites", "messages", "reactions", "notifications"`
+
+// Creates database with unique name and migrated schema for a test suite.
+// Returned function closes connections and drops the database. Panics on failure.
+func CreateDB(cfg TestConfig) (*gorm.DB, func()) {
+	setupDB, err := gorm.Open(postgres.Open(cfg.DBConnString))
+	if err != nil {
+		panic(err)
+	}
+
+	name := "gochat_test_db_" + uuid.NewString()
+	if err = setupDB.Exec(fmt.Sprintf(`CREATE DATABASE "%s"`, name)).Error; err != nil {
+		panic(err)
+	}
+
+	dbnameRegexp := regexp.MustCompile(`dbname=\S*`)
+	testDB, err := gorm.Open(postgres.Open(
+		dbnameRegexp.ReplaceAllString(cfg.DBConnString, "dbname="+name)))
+	if err != nil {
+		panic(err)
+	}
+
+	err = testDB.AutoMigrate(
+		models.User{},
+		models.Chat{},
+		models.ChatMember{},
+		models.ChatInvite{},
+		models.Message{},
+		models.Reaction{},
+		models.Notification{},
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	return testDB, func() { drop(setupDB, testDB, name) }
+}
+
+// Removes all records, so that tests don't affect each other. Panics on failure.
+func TruncateDB(db *gorm.DB) {
+	if err := db.Exec(truncateTablesQuery).Error; err != nil {
+		panic(err)
+	}
+}
+
+func drop(setupDB, testDB *gorm.DB, name string) {
+	testSQLDB, err := testDB.DB()
+	if err != nil {
+		log.Println(err)
+	}
+
+	if err = testSQLDB.Close(); err != nil {
+		log.Println(err)
+	}
+
+	err = setupDB.Exec(fmt.Sprintf(`DROP DATABASE "%s"`, name)).Error
+	if err != nil {
+		log.Println(err)
+	}
+
+	setupSQLDB, err := setupDB.DB()
+	if err != nil {
+		log.Println(err)
+	}
+
+	if err = setupSQLDB.Close(); err != nil {
+		log.Println(err)
+	}
+}
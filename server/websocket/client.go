@@ -1,10 +1,14 @@
 package websocket
 
 import (
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/shkotk/gochat/common/apimodels/events"
+	"github.com/shkotk/gochat/server/config"
+	"github.com/shkotk/gochat/server/interfaces"
 	"github.com/sirupsen/logrus"
 )
 
@@ -20,9 +24,6 @@ const (
 
 	// Maximum message size allowed from peer.
 	maxMessageSize = 1024
-
-	// Give up on sending event to writeQueue after this period.
-	writeEnqueueTimeout = time.Minute
 )
 
 var expectedCloseCodes = []int{
@@ -30,28 +31,58 @@ var expectedCloseCodes = []int{
 	websocket.CloseAbnormalClosure,
 }
 
-type Client struct {
-	username string
-	conn     *websocket.Conn
-
-	in   chan any
-	out  chan any
-	done chan struct{}
+// Creates clients sharing configuration and slow consumer statistics.
+type ClientFactory struct {
+	cfg                config.ClientConfig
+	slowConsumerEvents atomic.Int64
+	logger             *logrus.Logger
+}
 
-	logger *logrus.Logger
+func NewClientFactory(cfg config.Config, logger *logrus.Logger) *ClientFactory {
+	return &ClientFactory{cfg: cfg.Client, logger: logger}
 }
 
-func NewClient(username string, conn *websocket.Conn, logger *logrus.Logger) *Client {
-	client := &Client{
+func (f *ClientFactory) NewClient(username string, conn *websocket.Conn) *Client {
+	return &Client{
 		username: username,
 		conn:     conn,
 		in:       make(chan any),
-		out:      make(chan any),
+		queued:   make(chan struct{}, 1),
 		done:     make(chan struct{}),
-		logger:   logger,
+		closing:  make(chan struct{}),
+		factory:  f,
+		logger:   f.logger,
 	}
+}
 
-	return client
+// Gets number of events to which slow consumer policy was applied
+// since the server start.
+func (f *ClientFactory) SlowConsumerEvents() int64 {
+	return f.slowConsumerEvents.Load()
+}
+
+type Client struct {
+	username string
+	conn     *websocket.Conn
+
+	in   chan any
+	done chan struct{}
+
+	// Outgoing events in order they're written. Never exceeds configured queue size.
+	queue     []any
+	queueLock sync.Mutex
+	// Signalled when events are queued.
+	queued chan struct{}
+	// Set once queue gets full, reset once it's drained.
+	slow bool
+
+	closing   chan struct{}
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+
+	factory *ClientFactory
+	logger  *logrus.Logger
 }
 
 func (c *Client) ID() string {
@@ -62,14 +93,55 @@ func (c *Client) In() <-chan any {
 	return c.in
 }
 
-func (c *Client) Out() chan<- any {
-	return c.out
+// Queues event to be written to the peer without blocking. Configured slow consumer
+// policy is applied when queue is full.
+func (c *Client) Send(event any) {
+	c.queueLock.Lock()
+	defer c.queueLock.Unlock()
+
+	if len(c.queue) >= c.factory.cfg.QueueSize {
+		c.factory.slowConsumerEvents.Add(1)
+		if !c.slow {
+			c.slow = true
+			c.logger.Warnf("client: %s is a slow consumer, applying '%s' policy",
+				c.username, c.factory.cfg.SlowConsumerPolicy)
+		}
+
+		switch c.factory.cfg.SlowConsumerPolicy {
+		case config.DropOldest:
+			c.queue[0] = nil
+			c.queue = c.queue[1:]
+		case config.DropNew:
+			return
+		case config.Disconnect:
+			c.Close(interfaces.ClosePolicyViolation, "too slow to consume events")
+			return
+		}
+	}
+
+	c.queue = append(c.queue, event)
+
+	select {
+	case c.queued <- struct{}{}:
+	default:
+	}
 }
 
 func (c *Client) Done() <-chan struct{} {
 	return c.done
 }
 
+// Requests write loop to send close message with provided code and text and stop.
+// Already queued events are written before closing, unless client is closed due to
+// slow consumption.
+func (c *Client) Close(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeText = text
+		close(c.closing)
+	})
+}
+
 // Launches read and write loops in separate goroutines and waits for them to complete.
 func (c *Client) Run() {
 	defer close(c.done)
@@ -119,7 +191,11 @@ func (c *Client) startReading() <-chan struct{} {
 				continue
 			}
 
-			c.in <- event
+			select {
+			case c.in <- event:
+			case <-c.closing:
+				return
+			}
 		}
 	}()
 
@@ -140,17 +216,8 @@ func (c *Client) startWriting(cancel <-chan struct{}) <-chan struct{} {
 
 		for {
 			select {
-			case event := <-c.out:
-				message, err := events.Serialize(event)
-				if err != nil {
-					c.logger.WithError(err).Errorf(
-						"client: failed to serialize event of type %T, value: '%v'", event, event)
-					continue
-				}
-
-				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-				if err = c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-					c.logger.WithError(err).Warnf("client: error writing message to %s", c.username)
+			case <-c.queued:
+				if !c.writeQueued(time.Time{}) {
 					return
 				}
 
@@ -161,6 +228,21 @@ func (c *Client) startWriting(cancel <-chan struct{}) <-chan struct{} {
 					return
 				}
 
+			case <-c.closing:
+				deadline := time.Now().Add(writeWait)
+				if c.closeCode != interfaces.ClosePolicyViolation && !c.writeQueued(deadline) {
+					return
+				}
+
+				c.conn.SetWriteDeadline(deadline)
+				err := c.conn.WriteMessage(
+					websocket.CloseMessage,
+					websocket.FormatCloseMessage(c.closeCode, c.closeText))
+				if err != nil {
+					c.logger.WithError(err).Warnf("client: error sending close message to %s", c.username)
+				}
+				return
+
 			case <-cancel:
 				return
 			}
@@ -169,3 +251,55 @@ func (c *Client) startWriting(cancel <-chan struct{}) <-chan struct{} {
 
 	return done
 }
+
+// Writes queued events until queue is empty. Each write is limited by writeWait
+// unless non-zero deadline for all writes is provided, in which case writing isn't
+// interrupted by closing. Returns false if connection failed.
+func (c *Client) writeQueued(deadline time.Time) bool {
+	for {
+		if deadline.IsZero() && c.isClosing() {
+			return true // the rest is written by closing write loop
+		}
+
+		c.queueLock.Lock()
+		if len(c.queue) == 0 {
+			c.slow = false
+			c.queueLock.Unlock()
+			return true
+		}
+		event := c.queue[0]
+		c.queue[0] = nil
+		c.queue = c.queue[1:]
+		c.queueLock.Unlock()
+
+		frame, ok := event.(*Frame)
+		if !ok {
+			var err error
+			frame, err = NewFrame(event)
+			if err != nil {
+				c.logger.WithError(err).Errorf(
+					"client: failed to serialize event of type %T, value: '%v'", event, event)
+				continue
+			}
+		}
+
+		if deadline.IsZero() {
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		} else {
+			c.conn.SetWriteDeadline(deadline)
+		}
+		if err := c.conn.WritePreparedMessage(frame.message); err != nil {
+			c.logger.WithError(err).Warnf("client: error writing message to %s", c.username)
+			return false
+		}
+	}
+}
+
+func (c *Client) isClosing() bool {
+	select {
+	case <-c.closing:
+		return true
+	default:
+		return false
+	}
+}
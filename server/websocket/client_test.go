@@ -0,0 +1,53 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/shkotk/gochat/server/config"
+	"github.com/shkotk/gochat/server/interfaces"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(policy config.SlowConsumerPolicy) (*ClientFactory, *Client) {
+	factory := NewClientFactory(config.Config{
+		Client: config.ClientConfig{QueueSize: 2, SlowConsumerPolicy: policy},
+	}, logrus.StandardLogger())
+
+	return factory, factory.NewClient("jim", nil)
+}
+
+func TestClient_Send_FullQueueDropOldest_DropsOldestEvent(t *testing.T) {
+	factory, client := newTestClient(config.DropOldest)
+
+	client.Send(1)
+	client.Send(2)
+	client.Send(3)
+
+	assert.Equal(t, []any{2, 3}, client.queue)
+	assert.EqualValues(t, 1, factory.SlowConsumerEvents())
+}
+
+func TestClient_Send_FullQueueDropNew_DropsNewEvent(t *testing.T) {
+	factory, client := newTestClient(config.DropNew)
+
+	client.Send(1)
+	client.Send(2)
+	client.Send(3)
+
+	assert.Equal(t, []any{1, 2}, client.queue)
+	assert.EqualValues(t, 1, factory.SlowConsumerEvents())
+}
+
+func TestClient_Send_FullQueueDisconnect_ClosesClient(t *testing.T) {
+	factory, client := newTestClient(config.Disconnect)
+
+	client.Send(1)
+	client.Send(2)
+	client.Send(3)
+
+	assert.Equal(t, []any{1, 2}, client.queue)
+	assert.EqualValues(t, 1, factory.SlowConsumerEvents())
+	assert.True(t, client.isClosing())
+	assert.Equal(t, interfaces.ClosePolicyViolation, client.closeCode)
+}
@@ -0,0 +1,32 @@
+package websocket
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/shkotk/gochat/common/apimodels/events"
+)
+
+// Event serialized once, which can be written to any number of clients.
+// Frame is immutable and safe for concurrent use.
+type Frame struct {
+	event   any
+	message *websocket.PreparedMessage
+}
+
+func NewFrame(event any) (*Frame, error) {
+	data, err := events.Serialize(event)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := websocket.NewPreparedMessage(websocket.TextMessage, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Frame{event, message}, nil
+}
+
+// Gets event the frame was created from.
+func (f *Frame) Event() any {
+	return f.event
+}
@@ -3,12 +3,11 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 	"github.com/shkotk/gochat/server/config"
 )
 
-func InitializeRouter(cfg config.Config) *gin.Engine {
+func InitializeApplication(cfg config.Config) *application {
 	wire.Build(servicesSet)
-	return &gin.Engine{}
+	return &application{}
 }
@@ -7,24 +7,43 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
 	"github.com/shkotk/gochat/server/config"
 	"github.com/shkotk/gochat/server/controllers"
 	"github.com/shkotk/gochat/server/repositories"
 	"github.com/shkotk/gochat/server/services"
+	"github.com/shkotk/gochat/server/websocket"
 )
 
 // Injectors from wire.go:
 
-func InitializeRouter(cfg config.Config) *gin.Engine {
+func InitializeApplication(cfg config.Config) *application {
 	logger := setupLogger(cfg)
 	jwtManager := services.NewJWTManager(cfg)
+	requestRateLimiter := services.NewRequestRateLimiter(cfg)
 	db := setupDB(cfg, logger)
 	userRepository := repositories.NewUserRepository(logger, db)
 	userController := controllers.NewUserController(logger, userRepository, jwtManager)
-	eventPreProcessor := services.NewEventPreProcessor()
-	chatManager := services.NewChatManager(logger, eventPreProcessor)
-	chatController := controllers.NewChatController(logger, jwtManager, chatManager)
-	engine := setupRouter(cfg, logger, jwtManager, userController, chatController)
-	return engine
+	backplane := setupBackplane(cfg, db, logger)
+	clientRegistry := services.NewClientRegistry(backplane, logger)
+	eventRateLimiter := services.NewEventRateLimiter(cfg)
+	messageRepository := repositories.NewMessageRepository(logger, db)
+	chatMemberRepository := repositories.NewChatMemberRepository(logger, db)
+	eventValidator := services.NewEventValidator(messageRepository, chatMemberRepository)
+	chatRepository := repositories.NewChatRepository(logger, db)
+	commandRegistry := services.NewCommandRegistry(chatRepository, chatMemberRepository)
+	wordFilter := services.NewWordFilter(cfg)
+	mentionParser := services.NewMentionParser(userRepository)
+	v := setupEventProcessors(eventRateLimiter, eventValidator, commandRegistry, wordFilter, mentionParser)
+	eventProcessorChain := services.NewEventProcessorChain(cfg, logger, v, chatRepository)
+	reactionRepository := repositories.NewReactionRepository(logger, db)
+	notificationRepository := repositories.NewNotificationRepository(logger, db)
+	chatManager := services.NewChatManager(cfg, logger, backplane, clientRegistry, eventProcessorChain, chatRepository, chatMemberRepository, messageRepository, reactionRepository, notificationRepository)
+	clientFactory := websocket.NewClientFactory(cfg, logger)
+	chatInviteRepository := repositories.NewChatInviteRepository(logger, db)
+	chatController := controllers.NewChatController(logger, jwtManager, chatManager, clientRegistry, clientFactory, userRepository, chatRepository, chatMemberRepository, chatInviteRepository, messageRepository, reactionRepository, eventProcessorChain)
+	notificationController := controllers.NewNotificationController(logger, notificationRepository)
+	diagnosticsController := controllers.NewDiagnosticsController(cfg, logger, chatManager, clientFactory)
+	engine := setupRouter(cfg, logger, jwtManager, requestRateLimiter, userController, chatController, notificationController, diagnosticsController)
+	mainApplication := newApplication(engine, chatManager, clientRegistry, logger)
+	return mainApplication
 }